@@ -0,0 +1,221 @@
+package goload
+
+import (
+	"errors"
+	"fmt"
+	"github.com/scayle/goload/pacer"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CapacityResult is the outcome of a capacity search, see WithCapacitySearch.
+type CapacityResult struct {
+	// Rate is the highest rate in hits per second which stayed within all limits.
+	Rate float64 `json:"rate"`
+	// Converged is false if the load test ended before the search reached the configured precision.
+	Converged bool           `json:"converged"`
+	Steps     []CapacityStep `json:"steps"`
+}
+
+// CapacityStep is a single rate which was checked during a capacity search.
+type CapacityStep struct {
+	// Offset is the end of the step relative to the start of the run.
+	Offset  time.Duration     `json:"offset_ns"`
+	Rate    float64           `json:"rate"`
+	Passed  bool              `json:"passed"`
+	Results []ThresholdResult `json:"results"`
+}
+
+type CapacitySearchOption func(search *capacitySearch)
+
+// WithSearchStepDuration sets how long each rate is held before its results are checked. Defaults to 10s.
+func WithSearchStepDuration(d time.Duration) CapacitySearchOption {
+	return func(search *capacitySearch) {
+		search.stepDuration = d
+	}
+}
+
+// WithSearchIncrease sets the factor by which the rate is increased as long as no limit has failed. Defaults to 1.5.
+func WithSearchIncrease(factor float64) CapacitySearchOption {
+	return func(search *capacitySearch) {
+		search.increase = factor
+	}
+}
+
+// WithSearchPrecision sets the maximum distance between the highest passed and the lowest failed rate
+// relative to the failed rate at which the search ends. Defaults to 0.05.
+func WithSearchPrecision(ratio float64) CapacitySearchOption {
+	return func(search *capacitySearch) {
+		search.precision = ratio
+	}
+}
+
+// WithCapacitySearch searches the highest rate the target can sustain instead of using a fixed pacer.
+// Starting at startRate, the rate is increased in steps until one of the limits fails for the results
+// of a step. Then the rate is bisected between the highest passed and the lowest failed rate until the
+// precision is reached and the rate is held at the found capacity. Without a duration, the load test
+// ends once the search has converged.
+//
+// The limits are checked against the results of each step only, see ParseThreshold for the supported
+// limits. The capacity is included in the report.
+func WithCapacitySearch(startRate pacer.Rate, limits []Threshold, opts ...CapacitySearchOption) LoadTestOption {
+	search := &capacitySearch{
+		pacer:        pacer.NewAdjustablePacer(startRate),
+		limits:       limits,
+		stepDuration: 10 * time.Second,
+		increase:     1.5,
+		precision:    0.05,
+	}
+	search.rate = search.pacer.Rate(0)
+	for _, opt := range opts {
+		opt(search)
+	}
+	search.window.Store(newResultAggregator())
+
+	return func(options *LoadTestOptions) {
+		options.pacer = search.pacer
+		options.capacitySearch = search
+		options.resultHandlers = append(options.resultHandlers, search.handleResult)
+		options.hooks = append(options.hooks, search)
+	}
+}
+
+type capacitySearch struct {
+	pacer        *pacer.AdjustablePacer
+	limits       []Threshold
+	stepDuration time.Duration
+	increase     float64
+	precision    float64
+
+	// window aggregates the results of the current step
+	window atomic.Pointer[resultAggregator]
+
+	mu     sync.Mutex
+	rate   float64
+	lower  float64
+	upper  float64
+	result CapacityResult
+}
+
+func (s *capacitySearch) validate() error {
+	var errs []error
+	if len(s.limits) == 0 {
+		errs = append(errs, errors.New("capacity search needs at least one limit"))
+	}
+	for _, limit := range s.limits {
+		errs = append(errs, limit.err)
+	}
+	if s.stepDuration <= 0 {
+		errs = append(errs, errors.New("capacity search step duration must be > 0"))
+	}
+	if s.increase <= 1 {
+		errs = append(errs, errors.New("capacity search increase must be > 1"))
+	}
+	if s.precision <= 0 || s.precision >= 1 {
+		errs = append(errs, errors.New("capacity search precision must be between 0 and 1"))
+	}
+	if s.rate <= 0 || math.IsInf(s.rate, 0) || math.IsNaN(s.rate) {
+		errs = append(errs, errors.New("capacity search start rate must be > 0"))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *capacitySearch) handleResult(lt *LoadTest, result *Result) {
+	s.window.Load().resultAggregationHandler(lt, result)
+}
+
+func (s *capacitySearch) Start(lt *LoadTest) error {
+	go func() {
+		ticker := time.NewTicker(s.stepDuration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lt.done:
+				return
+			case <-ticker.C:
+				if s.step(lt) && lt.duration == 0 {
+					lt.Runner.Stop()
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *capacitySearch) Finish(_ *LoadTest, _ *Report) error {
+	return nil
+}
+
+// step checks the results of the current rate and moves on to the next one. It returns true once
+// the search has converged.
+func (s *capacitySearch) step(lt *LoadTest) bool {
+	window := s.window.Swap(newResultAggregator())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.result.Converged {
+		return true
+	}
+
+	// the step was held at the current rate, which is the target of achieved rate limits
+	stepPacer := pacer.NewAdjustablePacer(pacer.Rate{Per: time.Second})
+	stepPacer.SetRate(0, s.rate)
+	report := window.report(time.Time{}, s.stepDuration, pacer.ExpectedHits(stepPacer, s.stepDuration), lt.shares)
+
+	step := CapacityStep{
+		Offset: lt.Elapsed(),
+		Rate:   s.rate,
+		Passed: true,
+	}
+	for _, limit := range s.limits {
+		// a step without results has failed as well
+		result, _ := limit.evaluate(report)
+		step.Results = append(step.Results, result)
+		step.Passed = step.Passed && result.Passed
+	}
+	s.result.Steps = append(s.result.Steps, step)
+
+	if step.Passed {
+		s.lower = s.rate
+		s.result.Rate = s.rate
+	} else {
+		s.upper = s.rate
+	}
+
+	switch {
+	case s.upper == 0:
+		s.rate *= s.increase
+	case s.lower == 0:
+		s.rate /= s.increase
+	case (s.upper-s.lower)/s.upper <= s.precision:
+		s.rate = s.lower
+		s.result.Converged = true
+	default:
+		s.rate = (s.lower + s.upper) / 2
+	}
+	s.pacer.SetRate(lt.Elapsed(), s.rate)
+
+	return s.result.Converged
+}
+
+// capacity returns the outcome of the search so far.
+func (s *capacitySearch) capacity() *CapacityResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := s.result
+	result.Steps = append([]CapacityStep(nil), s.result.Steps...)
+	return &result
+}
+
+// formatCapacity returns a human-readable summary of the capacity search.
+func formatCapacity(result *CapacityResult) string {
+	if result.Converged {
+		return fmt.Sprintf("%.2f/s", result.Rate)
+	}
+	return fmt.Sprintf("%.2f/s (not converged after %d steps)", result.Rate, len(result.Steps))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/scayle/goload"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: goload compare [flags] <baseline> <candidate>")
+		fmt.Fprintln(fs.Output(), "\nBoth runs can be given as report (.json) or results file (.jsonl, .csv).")
+		fs.PrintDefaults()
+	}
+
+	latencyIncrease := fs.Float64("latency-increase", goload.DefaultTolerances.LatencyIncrease, "maximum relative increase of latency percentiles")
+	errorRateIncrease := fs.Float64("error-rate-increase", goload.DefaultTolerances.ErrorRateIncrease, "maximum absolute increase of the error rate")
+	rateDecrease := fs.Float64("rate-decrease", goload.DefaultTolerances.RateDecrease, "maximum relative decrease of the achieved rate")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	baseline, err := loadReport(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("can't load baseline: %w", err)
+	}
+	candidate, err := loadReport(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("can't load candidate: %w", err)
+	}
+
+	comparison := goload.CompareReports(baseline, candidate, goload.Tolerances{
+		LatencyIncrease:   *latencyIncrease,
+		ErrorRateIncrease: *errorRateIncrease,
+		RateDecrease:      *rateDecrease,
+	})
+	if err := comparison.WriteTable(os.Stdout); err != nil {
+		return err
+	}
+
+	if regressions := comparison.Regressions(); len(regressions) > 0 {
+		return errors.New("regressions found:\n  " + strings.Join(regressions, "\n  "))
+	}
+	return nil
+}
+
+// loadReport reads a stored report or rebuilds it from a results file.
+func loadReport(path string) (*goload.Report, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return goload.ReadReportFile(path)
+	}
+	return goload.ReportFromFile(path, goload.DetectResultFormat(path), goload.ResultFilter{})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const usage = `goload is a tool for working with goload load tests.
+
+Usage:
+
+	goload <command> [arguments]
+
+The commands are:
+
+	run       run a load test defined in a YAML or JSON file
+	report    rebuild the report of a run from a results file
+	compare   compare two runs and flag regressions
+`
+
+// errUsage is returned by commands which were called with invalid arguments after the usage was printed.
+var errUsage = errors.New("invalid arguments")
+
+type command struct {
+	name string
+	run  func(args []string) error
+}
+
+var commands = []command{
+	{name: "run", run: runRun},
+	{name: "report", run: runReport},
+	{name: "compare", run: runCompare},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			err := cmd.run(os.Args[2:])
+			switch {
+			case err == nil, errors.Is(err, flag.ErrHelp):
+			case errors.Is(err, errUsage):
+				os.Exit(2)
+			default:
+				fmt.Fprintf(os.Stderr, "goload %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "goload: unknown command %q\n\n%s", os.Args[1], usage)
+	os.Exit(2)
+}
+
+// parseFlags parses the flags of a command. Invalid flags are reported as errUsage, as the flag set
+// has printed the error and the usage already.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
+	}
+	return err
+}
+
+// stringsFlag is a flag which can be given multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/scayle/goload"
+	"os"
+	"time"
+)
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: goload report [flags] <results file>")
+		fs.PrintDefaults()
+	}
+
+	format := fs.String("format", "", "format of the results file (jsonl or csv), detected from the file extension by default")
+	var from, to timeFlag
+	fs.Var(&from, "from", "only include results after this time, either an offset from the first result (e.g. 1m) or RFC3339")
+	fs.Var(&to, "to", "only include results before this time, either an offset from the first result (e.g. 5m) or RFC3339")
+	var executors, thresholds stringsFlag
+	fs.Var(&executors, "executor", "only include results of this executor or group, can be repeated")
+	fs.Var(&thresholds, "threshold", "threshold to check, e.g. 'pi:p95<300ms' or 'error_rate<1%', can be repeated")
+	output := fs.String("o", "", "also store the report as JSON at this path, e.g. to compare it with other runs")
+	htmlOutput := fs.String("html", "", "also store the report as HTML page at this path")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	path := fs.Arg(0)
+
+	resultFormat := goload.ResultFormat(*format)
+	if resultFormat == "" {
+		resultFormat = goload.DetectResultFormat(path)
+	}
+
+	filter := goload.ResultFilter{
+		From:      from.offset,
+		To:        to.offset,
+		After:     from.time,
+		Before:    to.time,
+		Executors: executors,
+	}
+
+	parsedThresholds := make([]goload.Threshold, 0, len(thresholds))
+	for _, value := range thresholds {
+		threshold, err := goload.ParseThreshold(value)
+		if err != nil {
+			return err
+		}
+		parsedThresholds = append(parsedThresholds, threshold)
+	}
+
+	report, err := goload.ReportFromFile(path, resultFormat, filter)
+	if err != nil {
+		return err
+	}
+
+	thresholdErr := report.CheckThresholds(parsedThresholds...)
+	if err := report.WriteTable(os.Stdout); err != nil {
+		return err
+	}
+	if *output != "" {
+		if err := report.WriteJSONFile(*output); err != nil {
+			return err
+		}
+	}
+	if *htmlOutput != "" {
+		if err := report.WriteHTMLFile(*htmlOutput); err != nil {
+			return err
+		}
+	}
+
+	return thresholdErr
+}
+
+// timeFlag is either an offset relative to the start of a run or an absolute time.
+type timeFlag struct {
+	offset time.Duration
+	time   time.Time
+}
+
+func (f *timeFlag) String() string {
+	if !f.time.IsZero() {
+		return f.time.Format(time.RFC3339)
+	}
+	if f.offset > 0 {
+		return f.offset.String()
+	}
+	return ""
+}
+
+func (f *timeFlag) Set(value string) error {
+	if offset, err := time.ParseDuration(value); err == nil {
+		f.offset = offset
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("%q is neither a duration nor a RFC3339 time", value)
+	}
+	f.time = t
+	return nil
+}
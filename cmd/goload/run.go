@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/scayle/goload"
+	goload_config "github.com/scayle/goload/config"
+	goload_http "github.com/scayle/goload/http"
+	ctx_utils "github.com/scayle/goload/utils/ctx"
+	"os"
+)
+
+func runRun(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: goload run <test file> [overrides]")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "The test file defines the load test in YAML or JSON, see the config package.")
+		fmt.Fprintln(fs.Output(), "The overrides change its options, e.g. -duration 1m, run 'goload run <test file> -h' to list them.")
+		fs.PrintDefaults()
+	}
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	config, err := goload_config.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	opts, err := config.Options()
+	if err != nil {
+		return err
+	}
+
+	console := log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+	opts = append([]goload.LoadTestOption{goload.WithLogger(console)}, opts...)
+	// the flags following the test file override its options
+	goload_http.RegisterBasePathOverride()
+	lt, err := goload.New(append(opts, goload.WithOverrides(fs.Args()[1:]))...)
+	if err != nil {
+		return err
+	}
+
+	report, err := lt.Run(ctx_utils.ContextWithInterrupt(context.Background()))
+	if report != nil {
+		fmt.Println("summary:")
+		if err := report.WriteTable(os.Stdout); err != nil {
+			return err
+		}
+	}
+	return err
+}
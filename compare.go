@@ -0,0 +1,225 @@
+package goload
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Tolerances define how much worse a candidate run may be than its baseline before a
+// change is flagged as a regression.
+type Tolerances struct {
+	// LatencyIncrease is the maximum relative increase of each latency percentile, e.g. 0.1 for 10%.
+	LatencyIncrease float64
+	// ErrorRateIncrease is the maximum absolute increase of the error rate, e.g. 0.01 for one percentage point.
+	ErrorRateIncrease float64
+	// RateDecrease is the maximum relative decrease of the achieved rate, e.g. 0.05 for 5%.
+	RateDecrease float64
+}
+
+var DefaultTolerances = Tolerances{
+	LatencyIncrease:   0.1,
+	ErrorRateIncrease: 0.01,
+	RateDecrease:      0.05,
+}
+
+// Comparison contains the differences between a baseline and a candidate run.
+type Comparison struct {
+	Total StatsComparison
+	// Executors contains the comparison per executor, group and scenario. Each kind is in the
+	// order of the baseline, followed by those which only exist in the candidate.
+	Executors []StatsComparison
+}
+
+// StatsComparison compares the statistics of a single executor, group or the whole run.
+type StatsComparison struct {
+	Name string
+	// Missing is set if the executor only exists in one of the runs and can't be compared.
+	Missing string
+	// Regression is set if the executor has results in the baseline but not in the candidate.
+	Regression bool
+	Deltas     []MetricDelta
+}
+
+// MetricDelta is the change of a single metric. Latencies are given in seconds and
+// error rates as ratio (0-1).
+type MetricDelta struct {
+	Metric    string
+	Baseline  float64
+	Candidate float64
+	// Change is relative for rates and latencies and absolute for error rates.
+	Change     float64
+	Regression bool
+}
+
+// CompareReports compares the per-executor throughput, error rate and latency percentiles
+// of two runs and flags changes beyond the tolerances as regressions.
+func CompareReports(baseline *Report, candidate *Report, tolerances Tolerances) *Comparison {
+	comparison := &Comparison{
+		Total: compareStats(baseline.Total, candidate.Total, tolerances),
+	}
+
+	// executors, groups and scenarios may share names, so each is compared against its own kind
+	comparison.Executors = append(comparison.Executors, compareAll(baseline.Executors, candidate.Executors, tolerances)...)
+	comparison.Executors = append(comparison.Executors, compareAll(baseline.Groups, candidate.Groups, tolerances)...)
+	comparison.Executors = append(comparison.Executors, compareAll(baseline.Scenarios, candidate.Scenarios, tolerances)...)
+
+	return comparison
+}
+
+func compareAll(baseline []Stats, candidate []Stats, tolerances Tolerances) []StatsComparison {
+	candidateStats := make(map[string]Stats, len(candidate))
+	for _, stats := range candidate {
+		candidateStats[stats.Name] = stats
+	}
+
+	comparisons := make([]StatsComparison, 0, len(baseline))
+	seen := map[string]bool{}
+	for _, stats := range baseline {
+		seen[stats.Name] = true
+		other, ok := candidateStats[stats.Name]
+		if !ok {
+			comparisons = append(comparisons, StatsComparison{Name: stats.Name, Missing: "candidate", Regression: true})
+			continue
+		}
+		comparisons = append(comparisons, compareStats(stats, other, tolerances))
+	}
+
+	for _, stats := range candidate {
+		if !seen[stats.Name] {
+			comparisons = append(comparisons, StatsComparison{Name: stats.Name, Missing: "baseline"})
+		}
+	}
+	return comparisons
+}
+
+func compareStats(baseline Stats, candidate Stats, tolerances Tolerances) StatsComparison {
+	comparison := StatsComparison{
+		Name: baseline.Name,
+	}
+	if baseline.Requests == 0 || candidate.Requests == 0 {
+		comparison.Missing = "no results"
+		comparison.Regression = baseline.Requests > 0
+		return comparison
+	}
+
+	rate := relativeDelta("rate", baseline.Rate, candidate.Rate)
+	rate.Regression = rate.Change < -tolerances.RateDecrease
+	comparison.Deltas = append(comparison.Deltas, rate)
+
+	errorRate := MetricDelta{
+		Metric:    "error_rate",
+		Baseline:  baseline.ErrorRate,
+		Candidate: candidate.ErrorRate,
+		Change:    candidate.ErrorRate - baseline.ErrorRate,
+	}
+	errorRate.Regression = errorRate.Change > tolerances.ErrorRateIncrease
+	comparison.Deltas = append(comparison.Deltas, errorRate)
+
+	for _, p := range reportedPercentiles {
+		latency := relativeDelta("p"+formatPercentile(p), baseline.Latency.Percentile(p).Seconds(), candidate.Latency.Percentile(p).Seconds())
+		latency.Regression = latency.Change > tolerances.LatencyIncrease
+		comparison.Deltas = append(comparison.Deltas, latency)
+	}
+
+	latency := relativeDelta("max", baseline.Latency.Max.Seconds(), candidate.Latency.Max.Seconds())
+	latency.Regression = latency.Change > tolerances.LatencyIncrease
+	comparison.Deltas = append(comparison.Deltas, latency)
+
+	return comparison
+}
+
+func relativeDelta(metric string, baseline float64, candidate float64) MetricDelta {
+	delta := MetricDelta{
+		Metric:    metric,
+		Baseline:  baseline,
+		Candidate: candidate,
+	}
+	switch {
+	case baseline != 0:
+		delta.Change = (candidate - baseline) / baseline
+	case candidate != 0:
+		delta.Change = math.Inf(1)
+	}
+	return delta
+}
+
+// Regressions returns all metrics which changed beyond the tolerances, prefixed with the executor name.
+func (c *Comparison) Regressions() []string {
+	var regressions []string
+	for _, stats := range c.all() {
+		if stats.Regression {
+			regressions = append(regressions, fmt.Sprintf("%s: no results in the candidate", stats.Name))
+		}
+		for _, delta := range stats.Deltas {
+			if delta.Regression {
+				regressions = append(regressions, fmt.Sprintf("%s %s: %s", stats.Name, delta.Metric, delta.describe()))
+			}
+		}
+	}
+	return regressions
+}
+
+func (c *Comparison) all() []StatsComparison {
+	all := make([]StatsComparison, 0, len(c.Executors)+1)
+	all = append(all, c.Executors...)
+	return append(all, c.Total)
+}
+
+// WriteTable renders the comparison as a human-readable table.
+func (c *Comparison) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join([]string{"NAME", "METRIC", "BASELINE", "CANDIDATE", "CHANGE", ""}, "\t"))
+	for _, stats := range c.all() {
+		if stats.Missing != "" {
+			status := ""
+			if stats.Regression {
+				status = " REGRESSION"
+			}
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\tmissing in %s%s\n", stats.Name, stats.Missing, status)
+			continue
+		}
+		for _, delta := range stats.Deltas {
+			status := ""
+			if delta.Regression {
+				status = "REGRESSION"
+			}
+			fmt.Fprintln(tw, strings.Join([]string{
+				stats.Name,
+				delta.Metric,
+				delta.format(delta.Baseline),
+				delta.format(delta.Candidate),
+				delta.formatChange(),
+				status,
+			}, "\t"))
+		}
+	}
+
+	return tw.Flush()
+}
+
+func (d MetricDelta) describe() string {
+	return fmt.Sprintf("%s -> %s (%s)", d.format(d.Baseline), d.format(d.Candidate), d.formatChange())
+}
+
+func (d MetricDelta) format(value float64) string {
+	switch d.Metric {
+	case "rate":
+		return fmt.Sprintf("%.2f/s", value)
+	case "error_rate":
+		return fmt.Sprintf("%.2f%%", value*100)
+	default:
+		return roundLatency(time.Duration(value * float64(time.Second))).String()
+	}
+}
+
+func (d MetricDelta) formatChange() string {
+	if d.Metric == "error_rate" {
+		return fmt.Sprintf("%+.2fpp", d.Change*100)
+	}
+	return fmt.Sprintf("%+.2f%%", d.Change*100)
+}
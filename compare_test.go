@@ -0,0 +1,98 @@
+package goload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compareTestStats(name string, rate float64, errorRate float64, p95 time.Duration) Stats {
+	return Stats{
+		Name:      name,
+		Requests:  100,
+		Rate:      rate,
+		ErrorRate: errorRate,
+		Latency: LatencyDistribution{
+			Max:         p95,
+			Percentiles: []Percentile{{Percentile: 95, Value: p95}},
+		},
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	baseline := &Report{
+		Total: compareTestStats("all", 100, 0.01, 100*time.Millisecond),
+		Executors: []Stats{
+			compareTestStats("search", 50, 0.01, 100*time.Millisecond),
+			compareTestStats("checkout", 50, 0.01, 100*time.Millisecond),
+			compareTestStats("removed", 10, 0, 100*time.Millisecond),
+		},
+		Groups: []Stats{compareTestStats("shop", 100, 0.01, 100*time.Millisecond)},
+	}
+	candidate := &Report{
+		Total: compareTestStats("all", 98, 0.015, 105*time.Millisecond),
+		Executors: []Stats{
+			compareTestStats("search", 40, 0.01, 100*time.Millisecond),
+			compareTestStats("checkout", 50, 0.05, 150*time.Millisecond),
+			compareTestStats("added", 10, 0, 100*time.Millisecond),
+		},
+		Groups: []Stats{compareTestStats("shop", 100, 0.01, 100*time.Millisecond)},
+	}
+
+	comparison := CompareReports(baseline, candidate, DefaultTolerances)
+
+	expected := []string{
+		"search rate: 50.00/s -> 40.00/s (-20.00%)",
+		"checkout error_rate: 1.00% -> 5.00% (+4.00pp)",
+		"checkout p95: 100ms -> 150ms (+50.00%)",
+		"checkout max: 100ms -> 150ms (+50.00%)",
+		"removed: no results in the candidate",
+	}
+	if regressions := comparison.Regressions(); !reflect.DeepEqual(regressions, expected) {
+		t.Errorf("unexpected regressions:\n%s\nexpected:\n%s", strings.Join(regressions, "\n"), strings.Join(expected, "\n"))
+	}
+
+	var names []string
+	for _, stats := range comparison.Executors {
+		names = append(names, stats.Name+":"+stats.Missing)
+	}
+	if expected := []string{"search:", "checkout:", "removed:candidate", "added:baseline", "shop:"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("unexpected comparisons %v, expected %v", names, expected)
+	}
+}
+
+func TestCompareReportsWithinTolerances(t *testing.T) {
+	baseline := &Report{
+		Total:     compareTestStats("all", 100, 0.01, 100*time.Millisecond),
+		Executors: []Stats{compareTestStats("search", 100, 0.01, 100*time.Millisecond)},
+	}
+	candidate := &Report{
+		Total:     compareTestStats("all", 96, 0.015, 109*time.Millisecond),
+		Executors: []Stats{compareTestStats("search", 96, 0.015, 109*time.Millisecond)},
+	}
+
+	if regressions := CompareReports(baseline, candidate, DefaultTolerances).Regressions(); len(regressions) > 0 {
+		t.Errorf("unexpected regressions %v", regressions)
+	}
+}
+
+func TestCompareReportsPerKind(t *testing.T) {
+	// the group and the executor share their name but must not be compared with each other
+	baseline := &Report{
+		Executors: []Stats{compareTestStats("shop", 10, 0, 100*time.Millisecond)},
+		Groups:    []Stats{compareTestStats("shop", 100, 0, 100*time.Millisecond)},
+	}
+	candidate := &Report{
+		Executors: []Stats{compareTestStats("shop", 10, 0, 100*time.Millisecond)},
+		Groups:    []Stats{compareTestStats("shop", 100, 0, 100*time.Millisecond)},
+	}
+
+	comparison := CompareReports(baseline, candidate, DefaultTolerances)
+	if regressions := comparison.Regressions(); len(regressions) > 0 {
+		t.Errorf("unexpected regressions %v", regressions)
+	}
+	if len(comparison.Executors) != 2 {
+		t.Errorf("expected two comparisons, got %d", len(comparison.Executors))
+	}
+}
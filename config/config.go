@@ -0,0 +1,207 @@
+package goload_config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/scayle/goload"
+	goload_http "github.com/scayle/goload/http"
+	"github.com/scayle/goload/pacer"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config is the declarative definition of a load test with HTTP endpoints. It is read from YAML or JSON,
+// see Load, and turned into the options of a load test with Options. Custom executors still need Go code.
+type Config struct {
+	Duration Duration `yaml:"duration"`
+	// BasePath is prepended to the URLs of all endpoints, see goload_http.WithBasePath.
+	BasePath string       `yaml:"base_path"`
+	Pacer    *PacerConfig `yaml:"pacer"`
+	// Users replaces the pacer by virtual users.
+	Users           *UsersConfig     `yaml:"users"`
+	Workers         WorkersConfig    `yaml:"workers"`
+	ReportInterval  Duration         `yaml:"report_interval"`
+	DefaultTimeout  Duration         `yaml:"default_timeout"`
+	WeightOverrides map[string]int   `yaml:"weight_overrides"`
+	Endpoints       []EndpointConfig `yaml:"endpoints"`
+	Scenarios       []ScenarioConfig `yaml:"scenarios"`
+	// Thresholds are parsed with goload.ParseThreshold, e.g. "pi:p95<300ms".
+	Thresholds []string `yaml:"thresholds"`
+
+	ReportFile  string `yaml:"report_file"`
+	HTMLReport  string `yaml:"html_report"`
+	ResultsFile string `yaml:"results_file"`
+
+	// dir is the directory of the config file, which relative input paths are resolved against
+	dir string
+}
+
+type WorkersConfig struct {
+	Initial     int       `yaml:"initial"`
+	Max         int       `yaml:"max"`
+	IdleTimeout *Duration `yaml:"idle_timeout"`
+}
+
+// Load reads the config file at path. Both YAML and JSON are supported, as JSON is a subset of YAML.
+// Unknown fields are reported as an error.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	config.dir = filepath.Dir(path)
+	return config, nil
+}
+
+// Parse reads a config in YAML or JSON format. Relative paths in the config are resolved against the
+// working directory.
+func Parse(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("config is empty")
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Options returns the options of the load test which is defined by the config. Invalid endpoints
+// are reported when the load test is created.
+func (c *Config) Options() ([]goload.LoadTestOption, error) {
+	opts := []goload.LoadTestOption{
+		goload.WithDuration(time.Duration(c.Duration)),
+	}
+
+	if c.BasePath != "" {
+		opts = append(opts, goload_http.WithBasePath(c.BasePath))
+	}
+
+	if c.Pacer != nil {
+		p, err := c.Pacer.pacer(c.dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pacer: %w", err)
+		}
+		opts = append(opts, goload.WithPacer(p))
+	}
+	if c.Users != nil {
+		users, err := c.Users.options()
+		if err != nil {
+			return nil, fmt.Errorf("invalid users: %w", err)
+		}
+		opts = append(opts, users...)
+	}
+
+	if c.Workers.Initial > 0 {
+		opts = append(opts, goload.WithInitialWorkerCount(c.Workers.Initial))
+	}
+	if c.Workers.Max > 0 {
+		opts = append(opts, goload.WithMaxWorkerCount(c.Workers.Max))
+	}
+	if c.Workers.IdleTimeout != nil {
+		opts = append(opts, goload.WithWorkerIdleTimeout(time.Duration(*c.Workers.IdleTimeout)))
+	}
+	if c.ReportInterval > 0 {
+		opts = append(opts, goload.WithReportInterval(time.Duration(c.ReportInterval)))
+	}
+	if c.DefaultTimeout > 0 {
+		opts = append(opts, goload.WithDefaultTimeout(time.Duration(c.DefaultTimeout)))
+	}
+	if len(c.WeightOverrides) > 0 {
+		opts = append(opts, goload.WithWeightOverrides(c.WeightOverrides))
+	}
+
+	if len(c.Endpoints) == 0 && len(c.Scenarios) == 0 {
+		return nil, errors.New("at least one endpoint or scenario is required")
+	}
+	executors := make([]goload.Executor, 0, len(c.Endpoints)+len(c.Scenarios))
+	for i, endpoint := range c.Endpoints {
+		executor, err := endpoint.executor()
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %d: %w", i+1, err)
+		}
+		executors = append(executors, executor)
+	}
+	for i, scenario := range c.Scenarios {
+		executor, err := scenario.executor()
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario %d: %w", i+1, err)
+		}
+		executors = append(executors, executor)
+	}
+	opts = append(opts, goload.WithExecutors(executors...))
+
+	thresholds := make([]goload.Threshold, 0, len(c.Thresholds))
+	for _, value := range c.Thresholds {
+		threshold, err := goload.ParseThreshold(value)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	if len(thresholds) > 0 {
+		opts = append(opts, goload.WithThresholds(thresholds...))
+	}
+
+	if c.ReportFile != "" {
+		opts = append(opts, goload.WithReportFile(c.ReportFile))
+	}
+	if c.HTMLReport != "" {
+		opts = append(opts, goload.WithHTMLReport(c.HTMLReport))
+	}
+	if c.ResultsFile != "" {
+		opts = append(opts, goload.WithResultsFile(c.ResultsFile, goload.DetectResultFormat(c.ResultsFile)))
+	}
+
+	return opts, nil
+}
+
+// Duration is a duration in the format of time.ParseDuration, e.g. 1m30s.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Rate is a rate in the format of pacer.ParseRate, e.g. 10/s.
+type Rate pacer.Rate
+
+func (r *Rate) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	rate, err := pacer.ParseRate(s)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*r = Rate(rate)
+	return nil
+}
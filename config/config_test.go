@@ -0,0 +1,258 @@
+package goload_config
+
+import (
+	"github.com/scayle/goload"
+	"github.com/scayle/goload/pacer"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			input: `
+duration: 1m30s
+pacer: {type: constant, rate: 10/s}
+endpoints:
+  - url: http://localhost/health
+thresholds: ["p95<300ms"]
+`,
+		},
+		{
+			name:  "json",
+			input: `{"duration": "1m30s", "pacer": {"type": "constant", "rate": "10/s"}, "endpoints": [{"url": "http://localhost/health"}], "thresholds": ["p95<300ms"]}`,
+		},
+		{name: "empty", input: "", wantErr: "config is empty"},
+		{name: "unknown field", input: "duration: 1m\nrate: 10/s\n", wantErr: "field rate not found"},
+		{name: "invalid duration", input: "duration: soon\n", wantErr: "line 1"},
+		{name: "invalid rate", input: "duration: 1m\npacer:\n  type: constant\n  rate: fast\n", wantErr: "line 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Parse(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if time.Duration(config.Duration) != 90*time.Second {
+				t.Errorf("duration = %v, want 1m30s", time.Duration(config.Duration))
+			}
+			if config.Pacer == nil || config.Pacer.Type != "constant" || pacer.Rate(config.Pacer.Rate) != (pacer.Rate{Freq: 10, Per: time.Second}) {
+				t.Errorf("pacer = %+v, want a constant rate of 10/s", config.Pacer)
+			}
+			if len(config.Endpoints) != 1 || config.Endpoints[0].URL != "http://localhost/health" {
+				t.Errorf("endpoints = %+v", config.Endpoints)
+			}
+			if len(config.Thresholds) != 1 || config.Thresholds[0] != "p95<300ms" {
+				t.Errorf("thresholds = %v", config.Thresholds)
+			}
+
+			opts, err := config.Options()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := goload.New(opts...); err != nil {
+				t.Errorf("invalid load test: %v", err)
+			}
+		})
+	}
+}
+
+func TestPacerConfig(t *testing.T) {
+	perSec := func(freq int) pacer.Rate { return pacer.Rate{Freq: freq, Per: time.Second} }
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "trace.txt"), []byte("10\n0\n20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    pacer.Pacer
+		wantErr string
+	}{
+		{
+			name:  "constant",
+			input: "type: constant\nrate: 600/m\n",
+			want:  pacer.NewConstantPacer(perSec(10)),
+		},
+		{
+			name:  "ramp down",
+			input: "type: ramp\nstart_rate: 20/s\ntarget_rate: 0/s\nramp_duration: 10s\n",
+			want:  pacer.NewRampPacer(perSec(20), perSec(0), 10*time.Second),
+		},
+		{
+			name:  "step",
+			input: "type: step\nramp_duration: 5s\nsteps:\n  - {rate: 10/s, hold: 10s}\n  - {rate: 20/s}\n",
+			want:  pacer.NewStepPacer([]pacer.Step{{Rate: perSec(10), Hold: 10 * time.Second}, {Rate: perSec(20)}}, 5*time.Second),
+		},
+		{
+			name:  "sine",
+			input: "type: sine\nmean: 10/s\namplitude: 5/s\nperiod: 1m\nphase: 15s\n",
+			want:  pacer.NewSinePacer(perSec(10), perSec(5), time.Minute, 15*time.Second),
+		},
+		{
+			name:  "poisson",
+			input: "type: poisson\nrate: 10/s\nseed: 42\n",
+			want:  pacer.NewPoissonPacer(perSec(10), 42),
+		},
+		{
+			name:  "trace",
+			input: "type: trace\nfile: trace.txt\nformat: counts\nspeed: 2\nloop: true\n",
+			want:  pacer.NewTracePacer(pacer.TraceFromCounts([]int{10, 0, 20}, time.Second), 2, true),
+		},
+		{
+			name:  "stages",
+			input: "type: stages\nstages:\n  - {duration: 10s, pacer: {type: constant, rate: 10/s}}\n  - {pacer: {type: constant, rate: 20/s}}\n",
+			want: pacer.NewStagedPacer(
+				pacer.Stage{Pacer: pacer.NewConstantPacer(perSec(10)), Duration: 10 * time.Second},
+				pacer.Stage{Pacer: pacer.NewConstantPacer(perSec(20))},
+			),
+		},
+		{name: "no type", input: "rate: 10/s\n", wantErr: "type is required"},
+		{name: "unknown type", input: "type: burst\n", wantErr: `unknown type "burst"`},
+		{name: "no steps", input: "type: step\n", wantErr: "at least one step"},
+		{name: "no stages", input: "type: stages\n", wantErr: "at least one stage"},
+		{name: "no trace", input: "type: trace\n", wantErr: "needs a file"},
+		{name: "missing trace", input: "type: trace\nfile: missing.txt\n", wantErr: "can't read trace"},
+		{name: "invalid stage", input: "type: stages\nstages:\n  - {pacer: {type: burst}}\n", wantErr: "stage 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Load(writeConfig(t, dir, "duration: 1m\nendpoints: [{url: /}]\npacer:\n"+indent(tt.input)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = config.Options()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Options() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := config.Pacer.pacer(config.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, elapsed := range []time.Duration{0, 500 * time.Millisecond, 3 * time.Second, 12 * time.Second, time.Minute} {
+				if a, b := pacer.ExpectedHits(got, elapsed), pacer.ExpectedHits(tt.want, elapsed); math.Abs(a-b) > 1e-6 {
+					t.Errorf("ExpectedHits(%v) = %v, want %v", elapsed, a, b)
+				}
+			}
+			for hits := uint64(0); hits < 20; hits++ {
+				if a, b := pacer.NextHitAt(got, hits, 0), pacer.NextHitAt(tt.want, hits, 0); a != b {
+					t.Errorf("NextHitAt(%d) = %v, want %v", hits, a, b)
+				}
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name: "scenarios with users",
+			input: `
+duration: 1m
+users:
+  stages: [{target: 10, duration: 30s}]
+  think_time: {type: exponential, mean: 1s}
+scenarios:
+  - name: browse
+    weight: 2
+    think_time: {type: normal, mean: 1s, stddev: 100ms}
+    steps:
+      - url: http://localhost/
+      - url: http://localhost/search
+        query:
+          - {name: q, one_of: [a, b]}
+        extract:
+          - {variable: id, regex: 'id=(\d+)'}
+`,
+		},
+		{name: "no executors", input: "duration: 1m\n", wantErr: "at least one endpoint or scenario"},
+		{name: "no url", input: "duration: 1m\nendpoints: [{name: x}]\n", wantErr: "invalid endpoint 1: url is required"},
+		{name: "no scenario name", input: "duration: 1m\nscenarios: [{steps: [{url: /}]}]\n", wantErr: "invalid scenario 1: name is required"},
+		{name: "no steps", input: "duration: 1m\nscenarios: [{name: x}]\n", wantErr: "at least one step"},
+		{name: "invalid step", input: "duration: 1m\nscenarios: [{name: x, steps: [{}]}]\n", wantErr: "invalid step 1"},
+		{name: "no stages", input: "duration: 1m\nendpoints: [{url: /}]\nusers: {}\n", wantErr: "invalid users: at least one stage"},
+		{name: "invalid think time", input: "duration: 1m\nendpoints: [{url: /}]\nusers: {stages: [{target: 1}], think_time: {type: uniform, min: 2s, max: 1s}}\n", wantErr: "max must not be less than min"},
+		{name: "unknown think time", input: "duration: 1m\nendpoints: [{url: /}]\nusers: {stages: [{target: 1}], think_time: {type: random}}\n", wantErr: `unknown type "random"`},
+		{name: "extractor without source", input: "duration: 1m\nendpoints: [{url: /, extract: [{variable: x}]}]\n", wantErr: "exactly one of"},
+		{name: "invalid threshold", input: "duration: 1m\nendpoints: [{url: /}]\nthresholds: [latency]\n", wantErr: "invalid threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts, err := config.Options()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Options() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := goload.New(opts...); err != nil {
+				t.Errorf("invalid load test: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadExamples(t *testing.T) {
+	for _, name := range []string{"loadtest.yaml", "journey.yaml"} {
+		config, err := Load(filepath.Join("..", "examples", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.Options(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, dir string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func indent(s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return "  " + strings.Join(lines, "\n  ") + "\n"
+}
@@ -0,0 +1,240 @@
+package goload_config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/scayle/goload"
+	goload_http "github.com/scayle/goload/http"
+	"github.com/scayle/goload/http/url_builder"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EndpointConfig defines an HTTP endpoint, see goload_http.NewEndpoint. The name defaults to the path of the URL.
+type EndpointConfig struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Weight  int               `yaml:"weight"`
+	Timeout Duration          `yaml:"timeout"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Query contains query parameters with generated values, see url_builder.NewQueryParameter.
+	Query []QueryParamConfig `yaml:"query"`
+	// URLParams replaces each key in the URL by one of its values, see url_builder.WithURLParam.
+	URLParams map[string][]string `yaml:"url_params"`
+	Validate  ValidationConfig    `yaml:"validate"`
+	Extract   []ExtractorConfig   `yaml:"extract"`
+}
+
+// QueryParamConfig is a query parameter with exactly one of value, one_of, weighted, random_number or sample.
+type QueryParamConfig struct {
+	Name            string                `yaml:"name"`
+	Value           *string               `yaml:"value"`
+	OneOf           []string              `yaml:"one_of"`
+	Weighted        []WeightedValueConfig `yaml:"weighted"`
+	RandomNumber    *RangeConfig          `yaml:"random_number"`
+	Sample          *SampleConfig         `yaml:"sample"`
+	UsagePercentage *int                  `yaml:"usage_percentage"`
+}
+
+type WeightedValueConfig struct {
+	Value  string `yaml:"value"`
+	Weight int    `yaml:"weight"`
+}
+
+type RangeConfig struct {
+	Min int64 `yaml:"min"`
+	Max int64 `yaml:"max"`
+}
+
+// SampleConfig picks between min and max distinct values, see url_builder.WithSampledParamValues.
+type SampleConfig struct {
+	Min    int64    `yaml:"min"`
+	Max    int64    `yaml:"max"`
+	Values []string `yaml:"values"`
+}
+
+// ValidationConfig lists the expected status codes, either exact like 204 or classes like 2xx.
+// Without a status, a 2xx status is expected.
+type ValidationConfig struct {
+	Status []string `yaml:"status"`
+}
+
+// ExtractorConfig stores a value of the response as variable with exactly one of json_path, regex,
+// header or cookie, see goload_http.WithExtractors.
+type ExtractorConfig struct {
+	Variable string `yaml:"variable"`
+	JSONPath string `yaml:"json_path"`
+	Regex    string `yaml:"regex"`
+	Header   string `yaml:"header"`
+	Cookie   string `yaml:"cookie"`
+	ForUser  bool   `yaml:"for_user"`
+}
+
+func (c EndpointConfig) executor() (goload.Executor, error) {
+	if c.URL == "" {
+		return nil, errors.New("url is required")
+	}
+
+	opts := []goload_http.EndpointOption{
+		goload_http.WithTimeout(time.Duration(c.Timeout)),
+	}
+	if c.Name != "" {
+		opts = append(opts, goload_http.WithName(c.Name))
+	}
+	if c.Method != "" {
+		opts = append(opts, goload_http.WithMethod(strings.ToUpper(c.Method)))
+	}
+	if c.Weight != 0 {
+		opts = append(opts, goload_http.WithWeight(c.Weight))
+	}
+
+	if len(c.Query) > 0 || len(c.URLParams) > 0 {
+		builderOpts := []url_builder.URLBuilderOption{url_builder.WithRawURL(c.URL)}
+		for _, param := range c.Query {
+			queryParam, err := param.queryParam()
+			if err != nil {
+				return nil, err
+			}
+			builderOpts = append(builderOpts, url_builder.WithQueryParams(queryParam))
+		}
+		for key, values := range c.URLParams {
+			builderOpts = append(builderOpts, url_builder.WithURLParam(key, values))
+		}
+		opts = append(opts, goload_http.WithURLBuilder(builderOpts...))
+	} else {
+		opts = append(opts, goload_http.WithURL(c.URL))
+	}
+
+	if len(c.Headers) > 0 {
+		header := http.Header{}
+		for key, value := range c.Headers {
+			header.Set(key, value)
+		}
+		opts = append(opts, goload_http.WithHeader(header))
+	}
+	if c.Body != "" {
+		opts = append(opts, goload_http.WithBody(c.Body))
+	}
+
+	validation, err := c.Validate.validation()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, goload_http.WithValidateResponse(validation))
+
+	if len(c.Extract) > 0 {
+		extractors := make([]goload_http.Extractor, 0, len(c.Extract))
+		for _, extract := range c.Extract {
+			extractor, err := extract.extractor()
+			if err != nil {
+				return nil, err
+			}
+			extractors = append(extractors, extractor)
+		}
+		opts = append(opts, goload_http.WithExtractors(extractors...))
+	}
+
+	return goload_http.NewEndpoint(opts...), nil
+}
+
+func (c QueryParamConfig) queryParam() (url_builder.QueryParamBuilder, error) {
+	if c.Name == "" {
+		return nil, errors.New("query parameter needs a name")
+	}
+
+	var values []url_builder.QueryParameterOption
+	if c.Value != nil {
+		values = append(values, url_builder.WithParamValue(*c.Value))
+	}
+	if len(c.OneOf) > 0 {
+		values = append(values, url_builder.WithOneOfParamValue(c.OneOf))
+	}
+	if len(c.Weighted) > 0 {
+		weighted := make([]url_builder.WeightedValueOpt, 0, len(c.Weighted))
+		for _, value := range c.Weighted {
+			weighted = append(weighted, url_builder.WeightedValueOpt{Value: value.Value, Weight: value.Weight})
+		}
+		values = append(values, url_builder.WithWeightedParamValue(weighted...))
+	}
+	if c.RandomNumber != nil {
+		values = append(values, url_builder.WithRandomNumberParamValue(c.RandomNumber.Min, c.RandomNumber.Max))
+	}
+	if c.Sample != nil {
+		values = append(values, url_builder.WithSampledParamValues(c.Sample.Min, c.Sample.Max, c.Sample.Values))
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("query parameter %q needs exactly one of value, one_of, weighted, random_number or sample", c.Name)
+	}
+
+	opts := append([]url_builder.QueryParameterOption{url_builder.WithParamName(c.Name)}, values...)
+	if c.UsagePercentage != nil {
+		opts = append(opts, url_builder.WithParamUsagePercentage(*c.UsagePercentage))
+	}
+	return url_builder.NewQueryParameter(opts...), nil
+}
+
+func (c ValidationConfig) validation() (func(response *http.Response) error, error) {
+	patterns := c.Status
+	if len(patterns) == 0 {
+		patterns = []string{"2xx"}
+	}
+
+	// a class like 2xx is stored as the lowest code and 100 codes are matched
+	type statusRange struct{ min, max int }
+	ranges := make([]statusRange, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") && pattern[0] >= '1' && pattern[0] <= '5' {
+			class := int(pattern[0]-'0') * 100
+			ranges = append(ranges, statusRange{min: class, max: class + 99})
+			continue
+		}
+		code, err := strconv.Atoi(pattern)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q, expected a code like 200 or a class like 2xx", pattern)
+		}
+		ranges = append(ranges, statusRange{min: code, max: code})
+	}
+
+	expected := strings.Join(patterns, ", ")
+	return func(response *http.Response) error {
+		for _, r := range ranges {
+			if response.StatusCode >= r.min && response.StatusCode <= r.max {
+				return nil
+			}
+		}
+		return fmt.Errorf("unexpected status code %d, expected %s", response.StatusCode, expected)
+	}, nil
+}
+
+func (c ExtractorConfig) extractor() (goload_http.Extractor, error) {
+	if c.Variable == "" {
+		return goload_http.Extractor{}, errors.New("extractor needs a variable")
+	}
+
+	var extractors []goload_http.Extractor
+	if c.JSONPath != "" {
+		extractors = append(extractors, goload_http.JSONPathExtractor(c.Variable, c.JSONPath))
+	}
+	if c.Regex != "" {
+		extractors = append(extractors, goload_http.RegexExtractor(c.Variable, c.Regex))
+	}
+	if c.Header != "" {
+		extractors = append(extractors, goload_http.HeaderExtractor(c.Variable, c.Header))
+	}
+	if c.Cookie != "" {
+		extractors = append(extractors, goload_http.CookieExtractor(c.Variable, c.Cookie))
+	}
+	if len(extractors) != 1 {
+		return goload_http.Extractor{}, fmt.Errorf("extractor of %q needs exactly one of json_path, regex, header or cookie", c.Variable)
+	}
+
+	if c.ForUser {
+		return extractors[0].ForUser(), nil
+	}
+	return extractors[0], nil
+}
@@ -0,0 +1,114 @@
+package goload_config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/scayle/goload/pacer"
+	"path/filepath"
+	"time"
+)
+
+// PacerConfig defines the pacer of the load test. Which fields are used depends on the type:
+//
+//	constant  rate
+//	ramp      start_rate, target_rate, ramp_duration
+//	step      steps, ramp_duration
+//	sine      mean, amplitude, period, phase
+//	poisson   rate, seed
+//	trace     file, format, speed, loop
+//	stages    stages
+type PacerConfig struct {
+	Type string `yaml:"type"`
+
+	Rate         Rate         `yaml:"rate"`
+	StartRate    Rate         `yaml:"start_rate"`
+	TargetRate   Rate         `yaml:"target_rate"`
+	RampDuration Duration     `yaml:"ramp_duration"`
+	Steps        []StepConfig `yaml:"steps"`
+
+	Mean      Rate     `yaml:"mean"`
+	Amplitude Rate     `yaml:"amplitude"`
+	Period    Duration `yaml:"period"`
+	Phase     Duration `yaml:"phase"`
+
+	Seed int64 `yaml:"seed"`
+
+	// File is resolved relative to the directory of the config file.
+	File   string  `yaml:"file"`
+	Format string  `yaml:"format"`
+	Speed  float64 `yaml:"speed"`
+	Loop   bool    `yaml:"loop"`
+
+	Stages []StageConfig `yaml:"stages"`
+}
+
+type StepConfig struct {
+	Rate Rate     `yaml:"rate"`
+	Hold Duration `yaml:"hold"`
+}
+
+// StageConfig runs the pacer for the given duration, see pacer.Stage.
+type StageConfig struct {
+	Duration Duration    `yaml:"duration"`
+	Pacer    PacerConfig `yaml:"pacer"`
+}
+
+func (c PacerConfig) pacer(dir string) (pacer.Pacer, error) {
+	switch c.Type {
+	case "constant":
+		return pacer.NewConstantPacer(pacer.Rate(c.Rate)), nil
+	case "ramp":
+		return pacer.NewRampPacer(pacer.Rate(c.StartRate), pacer.Rate(c.TargetRate), time.Duration(c.RampDuration)), nil
+	case "step":
+		if len(c.Steps) == 0 {
+			return nil, errors.New("step pacer needs at least one step")
+		}
+		steps := make([]pacer.Step, 0, len(c.Steps))
+		for _, step := range c.Steps {
+			steps = append(steps, pacer.Step{Rate: pacer.Rate(step.Rate), Hold: time.Duration(step.Hold)})
+		}
+		return pacer.NewStepPacer(steps, time.Duration(c.RampDuration)), nil
+	case "sine":
+		return pacer.NewSinePacer(pacer.Rate(c.Mean), pacer.Rate(c.Amplitude), time.Duration(c.Period), time.Duration(c.Phase)), nil
+	case "poisson":
+		return pacer.NewPoissonPacer(pacer.Rate(c.Rate), c.Seed), nil
+	case "trace":
+		if c.File == "" {
+			return nil, errors.New("trace pacer needs a file")
+		}
+		path := c.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		format := pacer.TraceFormat(c.Format)
+		if format == "" {
+			format = pacer.TraceFormatTimestamps
+		}
+		speed := c.Speed
+		if speed == 0 {
+			speed = 1
+		}
+		trace, err := pacer.ReadTraceFile(path, format)
+		if err != nil {
+			return nil, fmt.Errorf("can't read trace: %w", err)
+		}
+		return pacer.NewTracePacer(trace, speed, c.Loop), nil
+	case "stages":
+		if len(c.Stages) == 0 {
+			return nil, errors.New("stages pacer needs at least one stage")
+		}
+		stages := make([]pacer.Stage, 0, len(c.Stages))
+		for i, stage := range c.Stages {
+			p, err := stage.Pacer.pacer(dir)
+			if err != nil {
+				return nil, fmt.Errorf("stage %d: %w", i+1, err)
+			}
+			stages = append(stages, pacer.Stage{Pacer: p, Duration: time.Duration(stage.Duration)})
+		}
+		return pacer.NewStagedPacer(stages...), nil
+	case "":
+		return nil, errors.New("type is required")
+	default:
+		return nil, fmt.Errorf("unknown type %q, expected constant, ramp, step, sine, poisson, trace or stages", c.Type)
+	}
+}
@@ -0,0 +1,120 @@
+package goload_config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/scayle/goload"
+	"time"
+)
+
+// ScenarioConfig defines a journey whose steps run in order and share variables, e.g. the values
+// stored by the extract option of an endpoint, see goload.WithScenario.
+type ScenarioConfig struct {
+	Name      string           `yaml:"name"`
+	Weight    int              `yaml:"weight"`
+	Timeout   Duration         `yaml:"timeout"`
+	ThinkTime *ThinkTimeConfig `yaml:"think_time"`
+	Steps     []EndpointConfig `yaml:"steps"`
+}
+
+// UsersConfig uses virtual users instead of a pacer, see goload.WithVirtualUsers.
+type UsersConfig struct {
+	Stages    []UserStageConfig `yaml:"stages"`
+	ThinkTime *ThinkTimeConfig  `yaml:"think_time"`
+}
+
+type UserStageConfig struct {
+	Target   int      `yaml:"target"`
+	Duration Duration `yaml:"duration"`
+}
+
+// ThinkTimeConfig defines a pause. Which fields are used depends on the type:
+//
+//	constant     duration
+//	uniform      min, max
+//	exponential  mean
+//	normal       mean, stddev
+type ThinkTimeConfig struct {
+	Type     string   `yaml:"type"`
+	Duration Duration `yaml:"duration"`
+	Min      Duration `yaml:"min"`
+	Max      Duration `yaml:"max"`
+	Mean     Duration `yaml:"mean"`
+	StdDev   Duration `yaml:"stddev"`
+}
+
+func (c ScenarioConfig) executor() (goload.Executor, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(c.Steps) == 0 {
+		return nil, errors.New("at least one step is required")
+	}
+
+	steps := make([]goload.Executor, 0, len(c.Steps))
+	for i, step := range c.Steps {
+		executor, err := step.executor()
+		if err != nil {
+			return nil, fmt.Errorf("invalid step %d: %w", i+1, err)
+		}
+		steps = append(steps, executor)
+	}
+
+	opts := []goload.ScenarioOption{
+		goload.WithScenarioName(c.Name),
+		goload.WithScenarioSteps(steps...),
+		goload.WithScenarioTimeout(time.Duration(c.Timeout)),
+	}
+	if c.Weight != 0 {
+		opts = append(opts, goload.WithScenarioWeight(c.Weight))
+	}
+	if c.ThinkTime != nil {
+		thinkTime, err := c.ThinkTime.thinkTime()
+		if err != nil {
+			return nil, fmt.Errorf("invalid think time: %w", err)
+		}
+		opts = append(opts, goload.WithScenarioThinkTime(thinkTime))
+	}
+	return goload.WithScenario(opts...), nil
+}
+
+func (c UsersConfig) options() ([]goload.LoadTestOption, error) {
+	if len(c.Stages) == 0 {
+		return nil, errors.New("at least one stage is required")
+	}
+
+	stages := make([]goload.UserStage, 0, len(c.Stages))
+	for _, stage := range c.Stages {
+		stages = append(stages, goload.UserStage{Target: stage.Target, Duration: time.Duration(stage.Duration)})
+	}
+	opts := []goload.LoadTestOption{goload.WithVirtualUsers(stages...)}
+
+	if c.ThinkTime != nil {
+		thinkTime, err := c.ThinkTime.thinkTime()
+		if err != nil {
+			return nil, fmt.Errorf("invalid think time: %w", err)
+		}
+		opts = append(opts, goload.WithThinkTime(thinkTime))
+	}
+	return opts, nil
+}
+
+func (c ThinkTimeConfig) thinkTime() (goload.ThinkTime, error) {
+	switch c.Type {
+	case "constant":
+		return goload.ConstantThinkTime(time.Duration(c.Duration)), nil
+	case "uniform":
+		if c.Max < c.Min {
+			return nil, errors.New("max must not be less than min")
+		}
+		return goload.UniformThinkTime(time.Duration(c.Min), time.Duration(c.Max)), nil
+	case "exponential":
+		return goload.ExponentialThinkTime(time.Duration(c.Mean)), nil
+	case "normal":
+		return goload.NormalThinkTime(time.Duration(c.Mean), time.Duration(c.StdDev)), nil
+	case "":
+		return nil, errors.New("type is required")
+	default:
+		return nil, fmt.Errorf("unknown type %q, expected constant, uniform, exponential or normal", c.Type)
+	}
+}
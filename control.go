@@ -0,0 +1,292 @@
+package goload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/scayle/goload/pacer"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNoRateControl is returned by the rate controls if the load test doesn't use a pacer or rate control
+// isn't enabled.
+var errNoRateControl = errors.New("rate control requires a pacer and WithRateControl")
+
+// ControlStatus is the current state of a running load test.
+type ControlStatus struct {
+	Elapsed      time.Duration `json:"elapsed_ns"`
+	ExpectedRate float64       `json:"expected_rate"`
+	Requests     int64         `json:"requests"`
+	Failures     int64         `json:"failures"`
+	Workers      int           `json:"workers"`
+	BusyWorkers  int           `json:"busy_workers"`
+	pacer.ControlState
+}
+
+// rateControl returns the pacer which is changed by the rate controls.
+func (lt *LoadTest) rateControl() (*pacer.ControlledPacer, error) {
+	if lt.coordinator != nil {
+		return nil, errors.New("the rate of a distributed load test can only be controlled on the agents")
+	}
+	if lt.control == nil {
+		return nil, errNoRateControl
+	}
+	return lt.control, nil
+}
+
+// SetRate replaces the pacer by a fixed rate in hits per second while the load test is running.
+// A paused load test stays paused until Resume is called.
+func (lt *LoadTest) SetRate(hitsPerSec float64) error {
+	control, err := lt.rateControl()
+	if err != nil {
+		return err
+	}
+	if hitsPerSec < 0 {
+		return errors.New("rate can't be negative")
+	}
+	control.SetRate(lt.Elapsed(), hitsPerSec)
+	return nil
+}
+
+// ScaleRate multiplies the current rate by the given factor, e.g. 1.1 to go 10% higher.
+func (lt *LoadTest) ScaleRate(factor float64) error {
+	control, err := lt.rateControl()
+	if err != nil {
+		return err
+	}
+	if factor <= 0 {
+		return errors.New("scale factor must be > 0")
+	}
+	control.Scale(lt.Elapsed(), factor)
+	return nil
+}
+
+// ResetRate goes back to the unscaled rate of the configured pacer.
+func (lt *LoadTest) ResetRate() error {
+	control, err := lt.rateControl()
+	if err != nil {
+		return err
+	}
+	control.Reset(lt.Elapsed())
+	return nil
+}
+
+// Pause stops sending hits until Resume is called. The duration of the load test isn't extended.
+func (lt *LoadTest) Pause() error {
+	control, err := lt.rateControl()
+	if err != nil {
+		return err
+	}
+	control.Pause(lt.Elapsed())
+	return nil
+}
+
+// Resume continues sending hits after Pause.
+func (lt *LoadTest) Resume() error {
+	control, err := lt.rateControl()
+	if err != nil {
+		return err
+	}
+	control.Resume(lt.Elapsed())
+	return nil
+}
+
+// Stop ends the load test early. Hits which are already running are completed and included in the report.
+func (lt *LoadTest) Stop() {
+	lt.Runner.Stop()
+}
+
+// ControlStatus returns the current state of the load test.
+func (lt *LoadTest) ControlStatus() ControlStatus {
+	elapsed := lt.Elapsed()
+	workers := lt.WorkerStats()
+	status := ControlStatus{
+		Elapsed:     elapsed,
+		Requests:    lt.resultAggregator.total.Load(),
+		Failures:    lt.resultAggregator.failures.Load(),
+		Workers:     workers.Live,
+		BusyWorkers: workers.Busy,
+	}
+	if lt.Pacer != nil {
+		status.ExpectedRate = lt.Pacer.Rate(elapsed)
+	}
+	if lt.control != nil {
+		status.ControlState = lt.control.State(elapsed)
+	}
+	return status
+}
+
+// runControlCommand executes a single command of the control interface.
+func (lt *LoadTest) runControlCommand(command string, arg string) error {
+	parseArg := func() (float64, error) {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(arg, "/s"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q for %s", arg, command)
+		}
+		return value, nil
+	}
+
+	switch command {
+	case "rate":
+		value, err := parseArg()
+		if err != nil {
+			return err
+		}
+		return lt.SetRate(value)
+	case "scale":
+		value, err := parseArg()
+		if err != nil {
+			return err
+		}
+		return lt.ScaleRate(value)
+	case "reset":
+		return lt.ResetRate()
+	case "pause":
+		return lt.Pause()
+	case "resume":
+		return lt.Resume()
+	case "stop":
+		lt.Stop()
+		return nil
+	case "status":
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// controlServer offers the control interface via HTTP.
+type controlServer struct {
+	addr     string
+	server   *http.Server
+	loadTest *LoadTest
+}
+
+// WithRateControl allows changing the rate of the pacer while the load test is running, e.g. with
+// LoadTest.SetRate. It is enabled by WithControlServer and WithStdinControl.
+func WithRateControl() LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.rateControl = true
+	}
+}
+
+// WithControlServer starts an HTTP server on addr to control the load test while it is running:
+//
+//	GET  /status                 current state as JSON
+//	POST /rate?value=50          replace the pacer by a fixed rate in hits per second
+//	POST /scale?value=1.1        multiply the current rate
+//	POST /reset                  go back to the configured pacer
+//	POST /pause, /resume, /stop
+//
+// All requests respond with the state after the change.
+func WithControlServer(addr string) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.rateControl = true
+		options.hooks = append(options.hooks, &controlServer{addr: addr})
+	}
+}
+
+func (s *controlServer) Start(lt *LoadTest) error {
+	s.loadTest = lt
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("can't start control server: %w", err)
+	}
+	s.server = &http.Server{Handler: s}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lt.logger.Error().Err(err).Msg("control server failed")
+		}
+	}()
+
+	return nil
+}
+
+func (s *controlServer) Finish(_ *LoadTest, _ *Report) error {
+	if s.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.server.Shutdown(ctx)
+}
+
+func (s *controlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	command := strings.Trim(r.URL.Path, "/")
+	if command != "status" && r.Method != http.MethodPost {
+		http.Error(w, "use POST to change the load test", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.loadTest.runControlCommand(command, r.URL.Query().Get("value")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.loadTest.ControlStatus()); err != nil {
+		s.loadTest.logger.Error().Err(err).Msg("failed to write control status")
+	}
+}
+
+// controlReader reads commands of the control interface line by line.
+type controlReader struct {
+	r io.Reader
+	w io.Writer
+}
+
+// WithStdinControl reads commands from stdin to control the load test while it is running:
+// "rate 50", "scale 1.1", "reset", "pause", "resume", "stop" and "status".
+func WithStdinControl() LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.rateControl = true
+		options.hooks = append(options.hooks, controlReader{r: os.Stdin, w: os.Stdout})
+	}
+}
+
+func (c controlReader) Start(lt *LoadTest) error {
+	go func() {
+		scanner := bufio.NewScanner(c.r)
+		for scanner.Scan() {
+			select {
+			case <-lt.done:
+				return
+			default:
+			}
+
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			arg := ""
+			if len(fields) > 1 {
+				arg = fields[1]
+			}
+
+			if err := lt.runControlCommand(fields[0], arg); err != nil {
+				fmt.Fprintf(c.w, "control: %v\n", err)
+				continue
+			}
+			status := lt.ControlStatus()
+			fmt.Fprintf(c.w, "control: expected rate %.2f/s, paused %t, %d requests, %d workers\n",
+				status.ExpectedRate, status.Paused, status.Requests, status.Workers)
+		}
+	}()
+	return nil
+}
+
+func (c controlReader) Finish(_ *LoadTest, _ *Report) error {
+	return nil
+}
@@ -0,0 +1,529 @@
+package goload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// agentStartDelay is the time between the last agent joining and the synchronized start, so all
+	// agents receive the start time before it has passed.
+	agentStartDelay = 2 * time.Second
+	// agentBatchInterval is the interval in which agents send their results to the coordinator.
+	agentBatchInterval = 500 * time.Millisecond
+	// agentTimeout is the time after which the coordinator gives up on an agent which hasn't sent results.
+	agentTimeout = 30 * time.Second
+	// agentRequestTimeout is the time after which an agent gives up on sending results to the coordinator.
+	agentRequestTimeout = 10 * time.Second
+	// agentMaxBufferedResults is the number of results an agent keeps while the coordinator can't be reached.
+	agentMaxBufferedResults = 100_000
+)
+
+// agentAssignment is the response of the coordinator to an agent which joined the load test.
+type agentAssignment struct {
+	// Agent is the index of the agent starting at 0.
+	Agent  int `json:"agent"`
+	Agents int `json:"agents"`
+	// StartAt is the wall clock time at which all agents start sending hits.
+	StartAt time.Time `json:"start_at"`
+}
+
+// agentBatch contains the results an agent has received since its last batch.
+type agentBatch struct {
+	Results    []resultRecord  `json:"results"`
+	Saturation SaturationStats `json:"saturation"`
+	Workers    WorkerStats     `json:"workers"`
+	// Done is set for the last batch after the agent has finished.
+	Done bool `json:"done"`
+}
+
+// agentReply is the response of the coordinator to a batch.
+type agentReply struct {
+	// Stop tells the agent to end the load test early.
+	Stop bool `json:"stop"`
+}
+
+// WithCoordinator makes the load test the coordinator of a distributed load test. It waits on addr
+// until the given number of agents have joined with WithAgent, starts them in sync and merges the
+// results they stream back into its own report, results file and thresholds. The coordinator doesn't
+// send hits itself.
+//
+// All agents run the same load test, each one sends an equal share of the rate of the pacer.
+// Stopping the coordinator stops all agents.
+func WithCoordinator(addr string, agents int) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		if agents <= 0 {
+			options.errs = append(options.errs, errors.New("coordinator needs at least one agent"))
+			return
+		}
+		options.coordinator = &coordinator{
+			addr:   addr,
+			agents: make([]*agentState, agents),
+		}
+		options.hooks = append(options.hooks, options.coordinator)
+	}
+}
+
+// WithAgent makes the load test an agent of the coordinator at coordinatorURL, e.g. "http://10.0.0.1:7070".
+// The agent joins the coordinator when it is run, waits for the synchronized start and sends its
+// results to the coordinator while it is running. If the coordinator can't be reached for a while,
+// the oldest results are dropped.
+func WithAgent(coordinatorURL string) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.agent = &agent{
+			url:    strings.TrimSuffix(coordinatorURL, "/"),
+			client: &http.Client{Timeout: agentRequestTimeout},
+		}
+		options.hooks = append(options.hooks, options.agent)
+		options.resultHandlers = append(options.resultHandlers, options.agent.handleResult)
+	}
+}
+
+// validateDistribution checks that the options can be used for a distributed load test.
+func validateDistribution(options LoadTestOptions) error {
+	if options.coordinator == nil && options.agent == nil {
+		return nil
+	}
+	if options.coordinator != nil && options.agent != nil {
+		return errors.New("a load test can't be coordinator and agent at the same time")
+	}
+	if options.pacer == nil {
+		return errors.New("distributed load tests require a pacer")
+	}
+	if options.capacitySearch != nil {
+		return errors.New("capacity search can't be combined with a distributed load test")
+	}
+	return nil
+}
+
+type agentState struct {
+	lastSeen   time.Time
+	done       bool
+	saturation SaturationStats
+	workers    WorkerStats
+}
+
+type coordinator struct {
+	addr   string
+	server *http.Server
+
+	loadTest *LoadTest
+	results  chan *Result
+	// joined is closed once all agents have joined
+	joined  chan struct{}
+	startAt time.Time
+	// agentDone is notified when an agent has sent its last batch
+	agentDone chan struct{}
+
+	mu       sync.Mutex
+	agents   []*agentState
+	started  bool
+	finished bool
+	// inflight counts the batches whose results are being handed to the load test
+	inflight sync.WaitGroup
+}
+
+func (c *coordinator) Start(lt *LoadTest) error {
+	c.loadTest = lt
+	c.results = make(chan *Result)
+	c.joined = make(chan struct{})
+	c.agentDone = make(chan struct{}, 1)
+
+	listener, err := net.Listen("tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("can't start coordinator: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/join", c.handleJoin)
+	mux.HandleFunc("/results", c.handleResults)
+	c.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := c.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lt.logger.Error().Err(err).Msg("coordinator failed")
+		}
+	}()
+
+	lt.logger.Info().Str("addr", listener.Addr().String()).Int("agents", len(c.agents)).Msg("waiting for agents")
+	return nil
+}
+
+func (c *coordinator) Finish(_ *LoadTest, _ *Report) error {
+	if c.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return c.server.Shutdown(ctx)
+}
+
+// run waits for all agents to join and returns the merged results of all agents. The channel is
+// closed once all agents have finished or were lost.
+func (c *coordinator) run(ctx context.Context) (<-chan *Result, error) {
+	select {
+	case <-c.joined:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("not all agents have joined: %w", ctx.Err())
+	}
+
+	c.loadTest.Runner.startedAt.Store(&c.startAt)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.loadTest.Runner.Stop()
+		case <-c.loadTest.done:
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for !c.checkAgents() {
+			select {
+			case <-ticker.C:
+			case <-c.agentDone:
+			}
+		}
+
+		c.mu.Lock()
+		c.finished = true
+		c.mu.Unlock()
+
+		c.inflight.Wait()
+		close(c.results)
+	}()
+
+	return c.results, nil
+}
+
+// checkAgents gives up on agents which haven't sent results for too long. It returns true once all
+// agents have finished.
+func (c *coordinator) checkAgents() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	finished := true
+	for i, agent := range c.agents {
+		if agent.done {
+			continue
+		}
+		if time.Since(agent.lastSeen) > agentTimeout {
+			c.loadTest.logger.Error().Int("agent", i).Msg("agent was lost, its remaining results are missing")
+			agent.done = true
+			continue
+		}
+		finished = false
+	}
+	return finished
+}
+
+func (c *coordinator) handleJoin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST to join", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	index := -1
+	joined := 0
+	for i, agent := range c.agents {
+		if agent == nil && index < 0 {
+			index = i
+			c.agents[i] = &agentState{}
+		}
+		if c.agents[i] != nil {
+			joined++
+		}
+	}
+	if index >= 0 && joined == len(c.agents) {
+		c.started = true
+		c.startAt = time.Now().Add(agentStartDelay)
+		for _, agent := range c.agents {
+			// agents aren't expected to send results before the start
+			agent.lastSeen = c.startAt
+		}
+		close(c.joined)
+	}
+	c.mu.Unlock()
+
+	if index < 0 {
+		http.Error(w, "all agents have joined already", http.StatusConflict)
+		return
+	}
+	c.loadTest.logger.Info().Int("agent", index).Str("remote", r.RemoteAddr).Msg("agent joined")
+
+	select {
+	case <-c.joined:
+	case <-r.Context().Done():
+		// the agent is gone before the start, its slot is free again
+		c.mu.Lock()
+		if !c.started {
+			c.agents[index] = nil
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	c.writeJSON(w, agentAssignment{
+		Agent:   index,
+		Agents:  len(c.agents),
+		StartAt: c.startAt,
+	})
+}
+
+func (c *coordinator) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST to send results", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var batch agentBatch
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, fmt.Sprintf("invalid batch: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(r.URL.Query().Get("agent"))
+	c.mu.Lock()
+	if err != nil || index < 0 || index >= len(c.agents) || c.agents[index] == nil {
+		c.mu.Unlock()
+		http.Error(w, "unknown agent", http.StatusBadRequest)
+		return
+	}
+	agent := c.agents[index]
+	if c.finished || agent.done {
+		c.mu.Unlock()
+		c.writeJSON(w, agentReply{Stop: true})
+		return
+	}
+	agent.lastSeen = time.Now()
+	agent.saturation = batch.Saturation
+	agent.workers = batch.Workers
+	agent.done = batch.Done
+	c.inflight.Add(1)
+	c.mu.Unlock()
+
+	for _, record := range batch.Results {
+		c.results <- record.result()
+	}
+	c.inflight.Done()
+
+	if batch.Done {
+		select {
+		case c.agentDone <- struct{}{}:
+		default:
+		}
+	}
+
+	stopped := false
+	select {
+	case <-c.loadTest.Runner.stopch:
+		stopped = true
+	default:
+	}
+	c.writeJSON(w, agentReply{Stop: stopped})
+}
+
+// saturation returns the late and dropped hits of all agents.
+func (c *coordinator) saturation() SaturationStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := SaturationStats{}
+	for _, agent := range c.agents {
+		if agent == nil {
+			continue
+		}
+		stats.Threshold = agent.saturation.Threshold
+		stats.Policy = agent.saturation.Policy
+		stats.LateTicks += agent.saturation.LateTicks
+		stats.DroppedTicks += agent.saturation.DroppedTicks
+	}
+	return stats
+}
+
+// workers returns the workers of all agents which are still running.
+func (c *coordinator) workers() WorkerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := WorkerStats{}
+	for _, agent := range c.agents {
+		if agent == nil || agent.done {
+			continue
+		}
+		stats.Live += agent.workers.Live
+		stats.Busy += agent.workers.Busy
+		stats.Idle += agent.workers.Idle
+	}
+	return stats
+}
+
+type agent struct {
+	url    string
+	client *http.Client
+
+	assignment agentAssignment
+	// sent is closed once the sender has stopped
+	sent chan struct{}
+
+	mu      sync.Mutex
+	records []resultRecord
+}
+
+func (a *agent) Start(_ *LoadTest) error {
+	return nil
+}
+
+// run joins the coordinator, waits for the synchronized start and runs the share of the agent.
+func (a *agent) run(ctx context.Context, lt *LoadTest) (<-chan *Result, error) {
+	if err := a.join(ctx); err != nil {
+		return nil, err
+	}
+
+	// each agent sends an equal share of the hits
+	lt.control.ScaleBase(1 / float64(a.assignment.Agents))
+
+	lt.logger.Info().Int("agent", a.assignment.Agent).Int("agents", a.assignment.Agents).Time("start_at", a.assignment.StartAt).Msg("joined coordinator")
+	timer := time.NewTimer(time.Until(a.assignment.StartAt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("load test was stopped before the start: %w", ctx.Err())
+	}
+
+	a.sent = make(chan struct{})
+	go func() {
+		defer close(a.sent)
+
+		ticker := time.NewTicker(agentBatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lt.done:
+				return
+			case <-ticker.C:
+				if err := a.send(context.Background(), lt, false); err != nil {
+					lt.logger.Error().Err(err).Msg("failed to send results to coordinator")
+				}
+			}
+		}
+	}()
+
+	return lt.Runner.Run(ctx, lt.Executors, lt.Pacer, lt.duration)
+}
+
+func (a *agent) join(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/join", nil)
+	if err != nil {
+		return err
+	}
+	// joining waits for all agents, so it is only bounded by the context
+	client := *a.client
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't join coordinator: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("can't join coordinator: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&a.assignment); err != nil {
+		return fmt.Errorf("invalid response of coordinator: %w", err)
+	}
+	return nil
+}
+
+func (a *agent) Finish(lt *LoadTest, _ *Report) error {
+	if a.sent == nil {
+		return nil
+	}
+	<-a.sent
+
+	ctx, cancel := context.WithTimeout(context.Background(), agentRequestTimeout)
+	defer cancel()
+	if err := a.send(ctx, lt, true); err != nil {
+		return fmt.Errorf("failed to send results to coordinator: %w", err)
+	}
+	return nil
+}
+
+func (a *agent) handleResult(_ *LoadTest, result *Result) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.records = append(a.records, newResultRecord(result))
+}
+
+// send sends all results since the last batch to the coordinator. The results are kept for
+// the next batch if the coordinator can't be reached.
+func (a *agent) send(ctx context.Context, lt *LoadTest, done bool) error {
+	a.mu.Lock()
+	records := a.records
+	a.records = nil
+	a.mu.Unlock()
+
+	body, err := json.Marshal(agentBatch{
+		Results:    records,
+		Saturation: lt.Runner.Saturation(),
+		Workers:    lt.Runner.WorkerStats(),
+		Done:       done,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/results?agent=%d", a.url, a.assignment.Agent), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err = errors.New(resp.Status)
+	}
+	if err != nil {
+		a.mu.Lock()
+		a.records = append(records, a.records...)
+		if dropped := len(a.records) - agentMaxBufferedResults; dropped > 0 {
+			// the oldest results are dropped, so the agent doesn't run out of memory
+			a.records = slices.Clone(a.records[dropped:])
+			err = fmt.Errorf("%w, dropped %d results", err, dropped)
+		}
+		a.mu.Unlock()
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply agentReply
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("invalid response of coordinator: %w", err)
+	}
+	if reply.Stop {
+		lt.Stop()
+	}
+	return nil
+}
+
+func (c *coordinator) writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		c.loadTest.logger.Error().Err(err).Msg("failed to write response")
+	}
+}
@@ -0,0 +1,95 @@
+package goload
+
+import (
+	"context"
+	"github.com/scayle/goload/pacer"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDistributedLoadTest(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	rate := pacer.NewConstantPacer(pacer.Rate{Freq: 100, Per: time.Second})
+	coordinator, err := New(
+		WithCoordinator(addr, 2),
+		WithPacer(rate),
+		WithDuration(2*time.Second),
+		WithExecutors(NewGenericExecutor("noop", func(ctx context.Context) error { return nil })),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report *Report
+	var coordinatorErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		report, coordinatorErr = coordinator.Run(context.Background())
+	}()
+	waitForListener(t, addr)
+
+	var hits [2]atomic.Int64
+	agentErrs := make([]error, len(hits))
+	for i := range hits {
+		i := i
+		agent, err := New(
+			WithAgent("http://"+addr),
+			WithPacer(rate),
+			WithDuration(2*time.Second),
+			WithExecutors(NewGenericExecutor("noop", func(ctx context.Context) error {
+				hits[i].Add(1)
+				return nil
+			})),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, agentErrs[i] = agent.Run(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	if coordinatorErr != nil {
+		t.Fatalf("coordinator failed: %v", coordinatorErr)
+	}
+	total := int64(0)
+	for i := range hits {
+		if agentErrs[i] != nil {
+			t.Fatalf("agent %d failed: %v", i, agentErrs[i])
+		}
+		// each agent sends half of the 200 expected hits
+		if count := hits[i].Load(); count < 80 || count > 120 {
+			t.Errorf("agent %d sent %d hits, expected about 100", i, count)
+		}
+		total += hits[i].Load()
+	}
+	if report.Total.Requests != total {
+		t.Errorf("coordinator counted %d requests, agents sent %d", report.Total.Requests, total)
+	}
+}
+
+func waitForListener(t *testing.T, addr string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	t.Fatalf("%s isn't listening", addr)
+}
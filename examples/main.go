@@ -1,15 +1,18 @@
 package main
 
 import (
-	"fmt"
 	"github.com/scayle/goload"
 	goload_http "github.com/scayle/goload/http"
 	"github.com/scayle/goload/http/url_builder"
 	"github.com/scayle/goload/pacer"
+	"os"
 	"time"
 )
 
 func main() {
+	// lets -base-path point the endpoints to another environment
+	goload_http.RegisterBasePathOverride()
+
 	goload.RunLoadTest(
 		goload.WithDuration(5*time.Minute),
 		//goload.WithLinearRampUpPacer(pacer.Rate{Freq: 30, Per: time.Minute}, pacer.Rate{Freq: 2, Per: time.Second}, 1*time.Minute),
@@ -35,12 +38,12 @@ func main() {
 				goload_http.WithValidateResponse(goload_http.Status2xxResponseValidation),
 			),
 		),
-		goload.WithAdditionalResultHandler(func(_ *goload.LoadTest, result *goload.Result) {
-			fmt.Printf("result: %+v\n", result)
-		}),
+		goload.WithResultsFile("results.jsonl", goload.ResultFormatJSONL),
 		goload.WithWeightOverrides(map[string]int{
 			"test": 1,
 			"pi":   2,
 		}),
+		// e.g. go run ./examples -duration 1m -pacer 10/s
+		goload.WithOverrides(os.Args[1:]),
 	)
 }
@@ -2,6 +2,7 @@ package goload
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -9,6 +10,11 @@ type ExecutionResponse struct {
 	Identifier     string
 	Err            error
 	AdditionalData any
+	// Steps contains the responses of the single steps if the executor runs a scenario.
+	Steps []StepResponse
+	// Groups contains the names of the groups the executor was picked from, the outermost first.
+	// It is set by the groups.
+	Groups []string
 }
 
 type ExecutorOptions struct {
@@ -22,3 +28,51 @@ type Executor interface {
 	// TODO: remove pointer and maybe split into two functions
 	Options() *ExecutorOptions
 }
+
+type invalidExecutor struct {
+	err error
+}
+
+// NewInvalidExecutor returns an Executor for constructors which can't return an error themselves.
+// The error is returned when the load test is created with New.
+func NewInvalidExecutor(err error) Executor {
+	return &invalidExecutor{err: err}
+}
+
+func (e *invalidExecutor) Execute(_ context.Context) ExecutionResponse {
+	return ExecutionResponse{
+		Identifier: e.Name(),
+		Err:        e.err,
+	}
+}
+
+func (e *invalidExecutor) Name() string {
+	return "invalid"
+}
+
+func (e *invalidExecutor) Options() *ExecutorOptions {
+	return &ExecutorOptions{}
+}
+
+func validateExecutor(ex Executor) error {
+	switch e := ex.(type) {
+	case *invalidExecutor:
+		return e.err
+	case *executorGroup:
+		for _, inner := range e.executors {
+			if err := validateExecutor(inner); err != nil {
+				return fmt.Errorf("invalid executor in group %q: %w", e.name, err)
+			}
+		}
+	case *scenario:
+		for _, step := range e.steps {
+			if _, ok := step.(*scenario); ok {
+				return fmt.Errorf("scenario %q can't contain another scenario", e.name)
+			}
+			if err := validateExecutor(step); err != nil {
+				return fmt.Errorf("invalid step in scenario %q: %w", e.name, err)
+			}
+		}
+	}
+	return nil
+}
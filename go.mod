@@ -9,6 +9,7 @@ require (
 	github.com/rs/zerolog v1.33.0
 	golang.org/x/sync v0.3.0
 	google.golang.org/grpc v1.58.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
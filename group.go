@@ -7,14 +7,17 @@ import (
 )
 
 type executorGroup struct {
-	name    string
-	chooser *weightedrand.Chooser[Executor, int]
-	weight  int
-	timeout time.Duration
+	name      string
+	chooser   *weightedrand.Chooser[Executor, int]
+	executors []Executor
+	weight    int
+	timeout   time.Duration
 }
 
 func (e *executorGroup) Execute(ctx context.Context) ExecutionResponse {
-	return e.chooser.Pick().Execute(ctx)
+	response := e.chooser.Pick().Execute(ctx)
+	response.Groups = append([]string{e.name}, response.Groups...)
+	return response
 }
 
 func (e *executorGroup) Name() string {
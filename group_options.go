@@ -1,8 +1,8 @@
 package goload
 
 import (
+	"fmt"
 	"github.com/mroth/weightedrand/v2"
-	"github.com/rs/zerolog/log"
 	"time"
 )
 
@@ -22,7 +22,7 @@ func WithGroup(opts ...GroupOption) Executor {
 	}
 
 	if len(options.executors) == 0 {
-		log.Fatal().Msg("group can't be empty")
+		return NewInvalidExecutor(fmt.Errorf("group %q can't be empty", options.name))
 	}
 	choises := make([]weightedrand.Choice[Executor, int], 0, len(options.executors))
 	for _, exec := range options.executors {
@@ -32,7 +32,7 @@ func WithGroup(opts ...GroupOption) Executor {
 		choises...,
 	)
 	if err != nil {
-		log.Fatal().Err(err).Msg("can't create chooser")
+		return NewInvalidExecutor(fmt.Errorf("can't create chooser for group %q: %w", options.name, err))
 	}
 
 	if options.weight == 0 {
@@ -44,10 +44,11 @@ func WithGroup(opts ...GroupOption) Executor {
 	}
 
 	return &executorGroup{
-		name:    options.name,
-		chooser: chooser,
-		weight:  options.weight,
-		timeout: options.timeout,
+		name:      options.name,
+		chooser:   chooser,
+		executors: options.executors,
+		weight:    options.weight,
+		timeout:   options.timeout,
 	}
 }
 
@@ -0,0 +1,266 @@
+package goload
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>goload report {{.StartedAt}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #ddd; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr.total td { font-weight: bold; }
+.pass { color: #2a7d2a; }
+.fail { color: #c0392b; font-weight: bold; }
+.charts { display: flex; flex-wrap: wrap; gap: 1em; }
+svg { background: #fafafa; border: 1px solid #eee; }
+svg text { font-size: 11px; fill: #555; }
+</style>
+</head>
+<body>
+<h1>goload report</h1>
+<p>Started at {{.StartedAt}}, duration {{.Duration}}</p>
+{{with .Saturation}}<p class="fail">Warning: {{.}}</p>
+{{end}}{{with .Capacity}}<p>Capacity: {{.}}</p>
+{{end}}{{with .VirtualUsers}}<p>Virtual users: {{.Peak}} peak, {{.Iterations}} iterations, {{printf "%.2f" .MinRate}}/s min, {{printf "%.2f" .MeanRate}}/s mean, {{printf "%.2f" .MaxRate}}/s max per user</p>
+{{end}}
+{{if .Thresholds}}
+<h2>Thresholds</h2>
+<table>
+<tr><th>Threshold</th><th>Actual</th><th>Result</th></tr>
+{{range .Thresholds}}<tr><td>{{.Description}}</td><td>{{.Actual}}</td>{{if .Passed}}<td class="pass">PASS</td>{{else}}<td class="fail">FAIL</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+
+<h2>Executors</h2>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr{{if .Total}} class="total"{{end}}>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+
+{{if .Charts}}
+<h2>Timeline</h2>
+<div class="charts">
+{{range .Charts}}{{.}}
+{{end}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+type htmlReportRow struct {
+	Cells []string
+	Total bool
+}
+
+// WriteHTML renders the report as a self-contained HTML page with charts of the timeline.
+func (r *Report) WriteHTML(w io.Writer) error {
+	header, rows := r.tableRows()
+
+	htmlRows := make([]htmlReportRow, 0, len(rows))
+	for i, row := range rows {
+		htmlRows = append(htmlRows, htmlReportRow{Cells: row, Total: i == len(rows)-1})
+	}
+
+	capacity := ""
+	if r.Capacity != nil {
+		capacity = formatCapacity(r.Capacity)
+	}
+
+	saturation := ""
+	if r.Saturation != nil && r.Saturation.Saturated() {
+		saturation = formatSaturation(r.Saturation)
+	}
+
+	return htmlReportTemplate.Execute(w, map[string]any{
+		"StartedAt":    r.StartedAt.Format(time.RFC3339),
+		"Duration":     r.Duration.Round(time.Millisecond),
+		"Thresholds":   r.Thresholds,
+		"VirtualUsers": r.VirtualUsers,
+		"Capacity":     capacity,
+		"Saturation":   saturation,
+		"Header":       header,
+		"Rows":         htmlRows,
+		"Charts":       r.timelineCharts(),
+	})
+}
+
+// WriteHTMLFile writes the report as HTML to the file at path.
+func (r *Report) WriteHTMLFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.WriteHTML(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// htmlReportWriter stores the final report of a load test as HTML.
+type htmlReportWriter struct {
+	path string
+}
+
+func (w htmlReportWriter) Start(_ *LoadTest) error {
+	return nil
+}
+
+func (w htmlReportWriter) Finish(_ *LoadTest, report *Report) error {
+	if err := report.WriteHTMLFile(w.path); err != nil {
+		return fmt.Errorf("failed to write html report: %w", err)
+	}
+	return nil
+}
+
+func (r *Report) timelineCharts() []template.HTML {
+	if len(r.Timeline) == 0 {
+		return nil
+	}
+
+	x := make([]float64, 0, len(r.Timeline))
+	expectedRate := make([]float64, 0, len(r.Timeline))
+	rate := make([]float64, 0, len(r.Timeline))
+	errorRate := make([]float64, 0, len(r.Timeline))
+	workers := make([]float64, 0, len(r.Timeline))
+	busyWorkers := make([]float64, 0, len(r.Timeline))
+	latencies := make([][]float64, len(timelinePercentiles))
+	for _, sample := range r.Timeline {
+		x = append(x, sample.Offset.Seconds())
+		expectedRate = append(expectedRate, sample.ExpectedRate)
+		rate = append(rate, sample.Rate)
+		errorRate = append(errorRate, sample.ErrorRate*100)
+		workers = append(workers, float64(sample.Workers))
+		busyWorkers = append(busyWorkers, float64(sample.BusyWorkers))
+		for i, percentile := range sample.Latency {
+			if i < len(latencies) {
+				latencies[i] = append(latencies[i], float64(percentile.Value)/float64(time.Millisecond))
+			}
+		}
+	}
+
+	latencySeries := make([]chartSeries, 0, len(timelinePercentiles))
+	for i, p := range timelinePercentiles {
+		latencySeries = append(latencySeries, chartSeries{name: "p" + formatPercentile(p), values: latencies[i]})
+	}
+
+	return []template.HTML{
+		lineChart("Rate (hits/s)", x, []chartSeries{
+			{name: "expected", values: expectedRate},
+			{name: "achieved", values: rate},
+		}),
+		lineChart("Latency (ms)", x, latencySeries),
+		lineChart("Error rate (%)", x, []chartSeries{{name: "errors", values: errorRate}}),
+		lineChart("Workers", x, []chartSeries{
+			{name: "live", values: workers},
+			{name: "busy", values: busyWorkers},
+		}),
+	}
+}
+
+type chartSeries struct {
+	name   string
+	values []float64
+}
+
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd"}
+
+const (
+	chartWidth   = 560
+	chartHeight  = 260
+	chartPadding = 48
+)
+
+// lineChart renders the series as SVG line chart with the x values given in seconds.
+func lineChart(title string, x []float64, series []chartSeries) template.HTML {
+	maxX := 0.0
+	if len(x) > 0 {
+		maxX = x[len(x)-1]
+	}
+	maxY := 0.0
+	for _, s := range series {
+		for _, v := range s.values {
+			maxY = math.Max(maxY, v)
+		}
+	}
+	maxY = niceCeil(maxY)
+	if maxX <= 0 {
+		maxX = 1
+	}
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	px := func(v float64) float64 { return chartPadding + v/maxX*plotWidth }
+	py := func(v float64) float64 { return chartHeight - chartPadding - v/maxY*plotHeight }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="20" style="font-size:13px;font-weight:bold;fill:#222">%s</text>`, chartPadding, html.EscapeString(title))
+
+	const ticks = 4
+	for i := 0; i <= ticks; i++ {
+		v := maxY / ticks * float64(i)
+		y := py(v)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e0e0e0"/>`, chartPadding, y, chartWidth-chartPadding, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`, chartPadding-6, y+4, formatAxisValue(v))
+
+		t := maxX / ticks * float64(i)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, px(t), chartHeight-chartPadding+16, time.Duration(t*float64(time.Second)).Round(time.Second))
+	}
+
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		points := make([]string, 0, len(s.values))
+		for j, v := range s.values {
+			if j < len(x) {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", px(x[j]), py(v)))
+			}
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, color, strings.Join(points, " "))
+
+		legendX := chartPadding + i*90
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, legendX, chartHeight-18, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, legendX+14, chartHeight-9, html.EscapeString(s.name))
+	}
+
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+// niceCeil rounds v up to 1, 2, 2.5 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, step := range []float64{1, 2, 2.5, 5, 10} {
+		if v <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatAxisValue(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2g", v)
+}
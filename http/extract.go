@@ -0,0 +1,236 @@
+package goload_http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/scayle/goload"
+	"github.com/scayle/goload/utils/random"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Extractor takes a value out of a response and stores it as a variable, so later requests can use it,
+// see goload.Variables.
+type Extractor struct {
+	variable string
+	// user stores the value in the variables of the virtual user instead of the ones of the hit
+	user    bool
+	extract func(response *http.Response, body []byte) (string, error)
+
+	err error
+}
+
+// ForUser stores the value in the variables of the virtual user, so it is kept between its iterations,
+// e.g. for a session token which is fetched once per user.
+func (e Extractor) ForUser() Extractor {
+	e.user = true
+	return e
+}
+
+// JSONPathExtractor extracts the value at path from a JSON response body. The supported subset of
+// JSONPath consists of the root `$`, child names `.name` or `['name']`, array indices `[0]` and
+// wildcards `[*]` or `.*`. If the path matches several values, one of them is picked at random.
+// Objects and arrays are stored as JSON.
+func JSONPathExtractor(variable string, path string) Extractor {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return Extractor{variable: variable, err: fmt.Errorf("invalid JSONPath %q: %w", path, err)}
+	}
+
+	return Extractor{
+		variable: variable,
+		extract: func(_ *http.Response, body []byte) (string, error) {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			var document any
+			if err := decoder.Decode(&document); err != nil {
+				return "", fmt.Errorf("invalid JSON body: %w", err)
+			}
+
+			matches := evaluateJSONPath(document, segments)
+			if len(matches) == 0 {
+				return "", fmt.Errorf("no match for %s", path)
+			}
+			return formatJSONValue(matches[random.Number(0, int64(len(matches)-1))])
+		},
+	}
+}
+
+// RegexExtractor extracts the first match of pattern in the response body. If the pattern contains a
+// capturing group, the value of the first group is stored instead of the whole match.
+func RegexExtractor(variable string, pattern string) Extractor {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Extractor{variable: variable, err: fmt.Errorf("invalid regex %q: %w", pattern, err)}
+	}
+
+	return Extractor{
+		variable: variable,
+		extract: func(_ *http.Response, body []byte) (string, error) {
+			match := re.FindSubmatch(body)
+			if match == nil {
+				return "", fmt.Errorf("no match for %s", pattern)
+			}
+			if len(match) > 1 {
+				return string(match[1]), nil
+			}
+			return string(match[0]), nil
+		},
+	}
+}
+
+// HeaderExtractor extracts the first value of the given response header.
+func HeaderExtractor(variable string, header string) Extractor {
+	return Extractor{
+		variable: variable,
+		extract: func(response *http.Response, _ []byte) (string, error) {
+			values := response.Header.Values(header)
+			if len(values) == 0 {
+				return "", fmt.Errorf("no header %s", header)
+			}
+			return values[0], nil
+		},
+	}
+}
+
+// CookieExtractor extracts the value of a cookie which is set by the response.
+func CookieExtractor(variable string, cookie string) Extractor {
+	return Extractor{
+		variable: variable,
+		extract: func(response *http.Response, _ []byte) (string, error) {
+			for _, c := range response.Cookies() {
+				if c.Name == cookie {
+					return c.Value, nil
+				}
+			}
+			return "", fmt.Errorf("no cookie %s", cookie)
+		},
+	}
+}
+
+// apply extracts the value from the response and stores it in vars.
+func (e Extractor) apply(vars *goload.Variables, response *http.Response, body []byte) error {
+	value, err := e.extract(response, body)
+	if err != nil {
+		return fmt.Errorf("can't extract %s: %w", e.variable, err)
+	}
+
+	if e.user {
+		vars = vars.User()
+	}
+	vars.Set(e.variable, value)
+	return nil
+}
+
+// jsonPathSegment selects a child of a JSON value, a wildcard selects all children.
+type jsonPathSegment struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New("path must start with $")
+	}
+	path = path[1:]
+
+	var segments []jsonPathSegment
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, ".*"):
+			segments = append(segments, jsonPathSegment{wildcard: true})
+			path = path[2:]
+		case path[0] == '.':
+			end := strings.IndexAny(path[1:], ".[")
+			if end < 0 {
+				end = len(path) - 1
+			}
+			name := path[1 : end+1]
+			if name == "" {
+				return nil, errors.New("empty name")
+			}
+			segments = append(segments, jsonPathSegment{name: name})
+			path = path[end+1:]
+		case path[0] == '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, errors.New("missing ]")
+			}
+			selector := path[1:end]
+			path = path[end+1:]
+
+			switch {
+			case selector == "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				segments = append(segments, jsonPathSegment{name: selector[1 : len(selector)-1]})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid selector [%s]", selector)
+				}
+				segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", path)
+		}
+	}
+	return segments, nil
+}
+
+func evaluateJSONPath(document any, segments []jsonPathSegment) []any {
+	values := []any{document}
+	for _, segment := range segments {
+		var next []any
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]any:
+				if segment.wildcard {
+					for _, child := range v {
+						next = append(next, child)
+					}
+				} else if child, ok := v[segment.name]; ok && !segment.isIndex {
+					next = append(next, child)
+				}
+			case []any:
+				switch {
+				case segment.wildcard:
+					next = append(next, v...)
+				case segment.isIndex:
+					index := segment.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+func formatJSONValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case nil:
+		return "", errors.New("value is null")
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
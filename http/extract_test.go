@@ -0,0 +1,146 @@
+package goload_http
+
+import (
+	"github.com/scayle/goload"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJSONPathExtractor(t *testing.T) {
+	body := `{
+		"access": "token",
+		"user": {"id": 42, "name": "Ada", "tags": ["a", "b"], "active": true, "manager": null},
+		"items": [{"id": 1, "price": 9.5}, {"id": 2, "price": 10}],
+		"weird key": {"x.y": "dotted"}
+	}`
+
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "$.access", want: []string{"token"}},
+		{path: "$.user.id", want: []string{"42"}},
+		{path: "$['user']['name']", want: []string{"Ada"}},
+		{path: `$["user"].active`, want: []string{"true"}},
+		{path: "$.user.tags", want: []string{`["a","b"]`}},
+		{path: "$.user.tags[1]", want: []string{"b"}},
+		{path: "$.user.tags[-1]", want: []string{"b"}},
+		{path: "$.items[0]", want: []string{`{"id":1,"price":9.5}`}},
+		{path: "$.items[*].id", want: []string{"1", "2"}},
+		{path: "$.items.*.price", want: []string{"9.5", "10"}},
+		{path: "$.user.tags[*]", want: []string{"a", "b"}},
+		{path: "$['weird key']['x.y']", want: []string{"dotted"}},
+		{path: "$.missing", wantErr: true},
+		{path: "$.user.tags[2]", wantErr: true},
+		{path: "$.user.id.value", wantErr: true},
+		{path: "$.items.id", wantErr: true},
+		{path: "$.user.manager", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			extractor := JSONPathExtractor("value", tt.path)
+			if extractor.err != nil {
+				t.Fatal(extractor.err)
+			}
+
+			// a path matching several values picks one of them
+			for i := 0; i < 10; i++ {
+				vars := goload.NewVariables()
+				err := extractor.apply(vars, httptest.NewRecorder().Result(), []byte(body))
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if got, _ := vars.Get("value"); err == nil && !slices.Contains(tt.want, got.(string)) {
+					t.Errorf("extracted %v, want one of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonPathSegment
+		wantErr bool
+	}{
+		{path: "$", want: nil},
+		{path: "$.a.b", want: []jsonPathSegment{{name: "a"}, {name: "b"}}},
+		{path: "$.a[0]['b c'][*].*", want: []jsonPathSegment{{name: "a"}, {index: 0, isIndex: true}, {name: "b c"}, {wildcard: true}, {wildcard: true}}},
+		{path: `$["a"][-2]`, want: []jsonPathSegment{{name: "a"}, {index: -2, isIndex: true}}},
+		{path: "a.b", wantErr: true},
+		{path: "$.", wantErr: true},
+		{path: "$..a", wantErr: true},
+		{path: "$[0", wantErr: true},
+		{path: "$[a]", wantErr: true},
+		{path: "$a", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseJSONPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseJSONPath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExtractors(t *testing.T) {
+	response := httptest.NewRecorder()
+	response.Header().Set("X-Request-Id", "abc")
+	http.SetCookie(response, &http.Cookie{Name: "session", Value: "s3cr3t"})
+	body := []byte(`<a href="/item?id=17">item</a>`)
+
+	tests := []struct {
+		name      string
+		extractor Extractor
+		want      string
+		wantErr   bool
+	}{
+		{name: "regex group", extractor: RegexExtractor("value", `id=(\d+)`), want: "17"},
+		{name: "regex match", extractor: RegexExtractor("value", `/item\S*"`), want: `/item?id=17"`},
+		{name: "regex without match", extractor: RegexExtractor("value", `id=x`), wantErr: true},
+		{name: "header", extractor: HeaderExtractor("value", "x-request-id"), want: "abc"},
+		{name: "missing header", extractor: HeaderExtractor("value", "X-Trace"), wantErr: true},
+		{name: "cookie", extractor: CookieExtractor("value", "session"), want: "s3cr3t"},
+		{name: "missing cookie", extractor: CookieExtractor("value", "user"), wantErr: true},
+		{name: "invalid JSON", extractor: JSONPathExtractor("value", "$.id"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := goload.NewVariables()
+			err := tt.extractor.apply(vars, response.Result(), body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := vars.String("value"); got != tt.want {
+				t.Errorf("extracted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithExtractors(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor Extractor
+		wantErr   string
+	}{
+		{name: "valid", extractor: JSONPathExtractor("id", "$.id")},
+		{name: "invalid path", extractor: JSONPathExtractor("id", "id"), wantErr: "invalid JSONPath"},
+		{name: "invalid regex", extractor: RegexExtractor("id", "("), wantErr: "invalid regex"},
+		{name: "zero value", extractor: Extractor{}, wantErr: "has to be created"},
+	}
+	for _, tt := range tests {
+		_, err := renderAndValidateOptions([]EndpointOption{WithURL("http://localhost/"), WithExtractors(tt.extractor)})
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package goload_http
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/rs/zerolog/log"
@@ -8,7 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 	"time"
 )
 
@@ -16,11 +17,16 @@ var basePath *string
 
 type EndpointOption func(ep *endpoint)
 
+// NewEndpoint creates an executor which sends an HTTP request on each execution.
+//
+// References to variables in the form ${name} in the URL and the header values are replaced by
+// the values extracted from earlier responses, see WithExtractors.
+//
+// Invalid options are reported when the load test is created.
 func NewEndpoint(opts ...EndpointOption) goload.Executor {
 	endpoint, err := renderAndValidateOptions(opts)
 	if err != nil {
-		fmt.Printf("Invalid Endpoint options: %v\n", err)
-		os.Exit(1)
+		return goload.NewInvalidExecutor(fmt.Errorf("invalid endpoint options: %w", err))
 	}
 
 	return endpoint
@@ -35,12 +41,15 @@ type endpoint struct {
 
 	client *http.Client
 
-	urlFunc     func() (*url.URL, error)
+	urlFunc     func(ctx context.Context) (*url.URL, error)
 	methodFunc  func() (string, error)
-	bodyFunc    func() (io.Reader, error)
-	headerFuncs []HeaderFunc
+	bodyFunc    func(ctx context.Context) (io.Reader, error)
+	headerFuncs []func(ctx context.Context) (http.Header, error)
 
 	validateResponse func(response *http.Response) error
+	extractors       []Extractor
+
+	err error
 }
 
 func (e *endpoint) Execute(ctx context.Context) goload.ExecutionResponse {
@@ -48,10 +57,15 @@ func (e *endpoint) Execute(ctx context.Context) goload.ExecutionResponse {
 		Identifier: e.name,
 	}
 
+	vars := goload.VariablesFromContext(ctx)
+	if vars == nil {
+		vars = goload.NewVariables()
+	}
+
 	var body io.Reader
 	if e.bodyFunc != nil {
 		var err error
-		body, err = e.bodyFunc()
+		body, err = e.bodyFunc(ctx)
 		if err != nil {
 			response.Err = err
 			log.Error().Err(err).Msg("failed to get body")
@@ -59,13 +73,13 @@ func (e *endpoint) Execute(ctx context.Context) goload.ExecutionResponse {
 		}
 	}
 
-	targetURL, err := e.urlFunc()
+	targetURL, err := e.urlFunc(ctx)
 	if err != nil {
 		response.Err = err
 		log.Error().Err(err).Msg("failed to get target URL")
 		return response
 	}
-	targetURLStr := targetURL.String()
+	targetURLStr := expandURL(targetURL, vars).String()
 
 	method, err := e.methodFunc()
 	if err != nil {
@@ -84,7 +98,7 @@ func (e *endpoint) Execute(ctx context.Context) goload.ExecutionResponse {
 	if len(e.headerFuncs) > 0 {
 		headers := http.Header{}
 		for _, headerFunc := range e.headerFuncs {
-			additionalHeader, err := headerFunc()
+			additionalHeader, err := headerFunc(ctx)
 			if err != nil {
 				response.Err = err
 				log.Error().Err(err).Msg("failed to get headers")
@@ -93,7 +107,7 @@ func (e *endpoint) Execute(ctx context.Context) goload.ExecutionResponse {
 
 			for key, values := range additionalHeader {
 				for _, value := range values {
-					headers.Add(key, value)
+					headers.Add(key, vars.Expand(value))
 				}
 			}
 		}
@@ -108,21 +122,89 @@ func (e *endpoint) Execute(ctx context.Context) goload.ExecutionResponse {
 		return response
 	}
 
-	defer res.Body.Close()
+	resBodyReader := res.Body
+	defer func() {
+		// the body is drained even if it wasn't read, so the connection can be reused
+		_, _ = io.Copy(io.Discard, resBodyReader)
+		resBodyReader.Close()
+	}()
 
 	response.AdditionalData = map[string]string{
 		"url": targetURLStr,
 	}
 
+	// the body is only kept if values have to be extracted from it
+	var resBody []byte
+	if len(e.extractors) > 0 {
+		resBody, err = io.ReadAll(res.Body)
+		if err != nil {
+			response.Err = fmt.Errorf("failed to read body: %w", err)
+			return response
+		}
+		res.Body = io.NopCloser(bytes.NewReader(resBody))
+	}
+
 	if e.validateResponse != nil {
 		if err := e.validateResponse(res); err != nil {
 			response.Err = err
+			return response
+		}
+	}
+
+	for _, extractor := range e.extractors {
+		if err := extractor.apply(vars, res, resBody); err != nil {
+			response.Err = err
+			return response
 		}
 	}
 
 	return response
 }
 
+// expandURL replaces references to variables in the path and the query of u, see goload.Variables.Expand.
+func expandURL(u *url.URL, vars *goload.Variables) *url.URL {
+	path := vars.Expand(u.Path)
+	query := u.RawQuery
+	if strings.Contains(query, "${") || strings.Contains(query, "%24%7B") {
+		// only the parts with references are replaced, so the order and encoding of the others is kept
+		parts := strings.Split(query, "&")
+		for i, part := range parts {
+			key, value, found := strings.Cut(part, "=")
+			key = expandQueryPart(key, vars)
+			if found {
+				key += "=" + expandQueryPart(value, vars)
+			}
+			parts[i] = key
+		}
+		query = strings.Join(parts, "&")
+	}
+	if path == u.Path && query == u.RawQuery {
+		return u
+	}
+
+	expanded := *u
+	expanded.Path = path
+	expanded.RawPath = ""
+	expanded.RawQuery = query
+	return &expanded
+}
+
+// expandQueryPart replaces references to variables in an escaped key or value of a query.
+func expandQueryPart(part string, vars *goload.Variables) string {
+	if !strings.Contains(part, "${") && !strings.Contains(part, "%24%7B") {
+		return part
+	}
+	unescaped, err := url.QueryUnescape(part)
+	if err != nil {
+		return part
+	}
+	expanded := vars.Expand(unescaped)
+	if expanded == unescaped {
+		return part
+	}
+	return url.QueryEscape(expanded)
+}
+
 func (e *endpoint) Name() string {
 	return e.name
 }
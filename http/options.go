@@ -1,12 +1,15 @@
 package goload_http
 
 import (
+	"context"
 	"errors"
 	"github.com/scayle/goload"
 	"github.com/scayle/goload/http/url_builder"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,7 +24,7 @@ func renderAndValidateOptions(opts []EndpointOption) (*endpoint, error) {
 			return http.MethodGet, nil
 		},
 		bodyFunc:         nil,
-		headerFuncs:      []HeaderFunc{},
+		headerFuncs:      []func(ctx context.Context) (http.Header, error){},
 		validateResponse: nil,
 	}
 
@@ -29,12 +32,16 @@ func renderAndValidateOptions(opts []EndpointOption) (*endpoint, error) {
 		opt(&endpoint)
 	}
 
+	if endpoint.err != nil {
+		return nil, endpoint.err
+	}
+
 	if endpoint.urlFunc == nil {
 		return nil, errors.New("urlFunc is required")
 	}
 
 	if endpoint.name == "" {
-		targetURL, err := endpoint.urlFunc()
+		targetURL, err := endpoint.urlFunc(context.Background())
 		if err != nil {
 			return nil, err
 		}
@@ -70,15 +77,16 @@ func WithClient(client http.Client) EndpointOption {
 
 func WithURL(rawURL string) EndpointOption {
 	return func(ep *endpoint) {
-		ep.urlFunc = func() (*url.URL, error) {
+		ep.urlFunc = func(_ context.Context) (*url.URL, error) {
+			targetURL := rawURL
 			if basePath != nil {
 				var err error
-				rawURL, err = url.JoinPath(*basePath, rawURL)
+				targetURL, err = url.JoinPath(*basePath, rawURL)
 				if err != nil {
 					return nil, err
 				}
 			}
-			u, err := url.Parse(rawURL)
+			u, err := url.Parse(targetURL)
 			if err != nil {
 				return nil, err
 			}
@@ -88,6 +96,16 @@ func WithURL(rawURL string) EndpointOption {
 }
 
 func WithURLFunc(urlFunc func() (*url.URL, error)) EndpointOption {
+	return func(ep *endpoint) {
+		ep.urlFunc = func(_ context.Context) (*url.URL, error) {
+			return urlFunc()
+		}
+	}
+}
+
+// WithURLContextFunc builds the URL of each request from the context of the hit, which carries the
+// variables extracted from earlier responses, see goload.VariablesFromContext.
+func WithURLContextFunc(urlFunc func(ctx context.Context) (*url.URL, error)) EndpointOption {
 	return func(ep *endpoint) {
 		ep.urlFunc = urlFunc
 	}
@@ -108,20 +126,49 @@ func WithMethodFunc(methodFunc func() (string, error)) EndpointOption {
 }
 
 func WithBodyFunc(bodyFunc func() (io.Reader, error)) EndpointOption {
+	return func(ep *endpoint) {
+		ep.bodyFunc = func(_ context.Context) (io.Reader, error) {
+			return bodyFunc()
+		}
+	}
+}
+
+// WithBodyContextFunc creates the body of each request from the context of the hit, see WithURLContextFunc.
+func WithBodyContextFunc(bodyFunc func(ctx context.Context) (io.Reader, error)) EndpointOption {
 	return func(ep *endpoint) {
 		ep.bodyFunc = bodyFunc
 	}
 }
 
+// WithBody sends the given body. References to variables in the form ${name} are replaced by their values.
+func WithBody(body string) EndpointOption {
+	return func(ep *endpoint) {
+		ep.bodyFunc = func(ctx context.Context) (io.Reader, error) {
+			return strings.NewReader(goload.VariablesFromContext(ctx).Expand(body)), nil
+		}
+	}
+}
+
+// WithHeader adds the given headers. References to variables in the form ${name} in the values are
+// replaced by their values, as for the URL.
 func WithHeader(header http.Header) EndpointOption {
 	return func(ep *endpoint) {
-		ep.headerFuncs = append(ep.headerFuncs, func() (http.Header, error) {
+		ep.headerFuncs = append(ep.headerFuncs, func(_ context.Context) (http.Header, error) {
 			return header, nil
 		})
 	}
 }
 
 func WithHeaderFunc(headerFunc func() (http.Header, error)) EndpointOption {
+	return func(ep *endpoint) {
+		ep.headerFuncs = append(ep.headerFuncs, func(_ context.Context) (http.Header, error) {
+			return headerFunc()
+		})
+	}
+}
+
+// WithHeaderContextFunc adds the headers created from the context of the hit, see WithURLContextFunc.
+func WithHeaderContextFunc(headerFunc func(ctx context.Context) (http.Header, error)) EndpointOption {
 	return func(ep *endpoint) {
 		ep.headerFuncs = append(ep.headerFuncs, headerFunc)
 	}
@@ -133,10 +180,32 @@ func WithValidateResponse(validationFunc func(response *http.Response) error) En
 	}
 }
 
+// WithExtractors stores values of each successful response as variables, which can be used by the
+// following requests of the same scenario or virtual user. An extraction which fails fails the request.
+func WithExtractors(extractors ...Extractor) EndpointOption {
+	return func(ep *endpoint) {
+		for _, extractor := range extractors {
+			if extractor.err != nil {
+				ep.err = extractor.err
+				return
+			}
+			if extractor.extract == nil {
+				ep.err = errors.New("extractor has to be created with a function like JSONPathExtractor")
+				return
+			}
+		}
+		ep.extractors = append(ep.extractors, extractors...)
+	}
+}
+
 func WithURLBuilder(opts ...url_builder.URLBuilderOption) EndpointOption {
 	builder := url_builder.NewURLBuilder(opts)
 	return func(ep *endpoint) {
-		ep.urlFunc = func() (*url.URL, error) {
+		if err := builder.Err(); err != nil {
+			ep.err = err
+			return
+		}
+		ep.urlFunc = func(_ context.Context) (*url.URL, error) {
 			return builder.Build(basePath)
 		}
 	}
@@ -147,3 +216,15 @@ func WithBasePath(path string) goload.LoadTestOption {
 		basePath = &path
 	}
 }
+
+var registerBasePathOverride sync.Once
+
+// RegisterBasePathOverride adds the -base-path flag to goload.WithOverrides, which replaces the base path
+// like WithBasePath. It has to be called before WithOverrides, further calls are ignored.
+func RegisterBasePathOverride() {
+	registerBasePathOverride.Do(func() {
+		goload.RegisterOverride("base-path", "base path of the HTTP endpoints, e.g. https://staging.example.com", func(value string) (goload.LoadTestOption, error) {
+			return WithBasePath(value), nil
+		})
+	})
+}
@@ -1,8 +1,9 @@
 package url_builder
 
 import (
+	"errors"
+	"fmt"
 	"github.com/mroth/weightedrand/v2"
-	"github.com/rs/zerolog/log"
 	"github.com/scayle/goload/utils/random"
 	"net/url"
 )
@@ -19,6 +20,8 @@ type QueryParameter struct {
 	Name         NameFn
 	ShouldBeUsed ShouldBeUsedFn
 	Value        ValuesFn
+
+	err error
 }
 
 type QueryParameterOption func(param *QueryParameter)
@@ -31,12 +34,16 @@ func NewQueryParameter(opts ...QueryParameterOption) *QueryParameter {
 	for _, opt := range opts {
 		opt(param)
 	}
-	if param.Name == nil || param.Value == nil {
-		log.Fatal().Msg("NewQueryParameter must contain opts for name and value")
+	if param.err == nil && (param.Name == nil || param.Value == nil) {
+		param.err = errors.New("NewQueryParameter must contain opts for name and value")
 	}
 	return param
 }
 
+func (s *QueryParameter) validate() error {
+	return s.err
+}
+
 func (s *QueryParameter) Build() url.Values {
 	if !s.ShouldBeUsed() {
 		return url.Values{}
@@ -60,11 +67,20 @@ type oneOfParam struct {
 
 func WithOneOfParam(params ...QueryParamBuilder) QueryParamBuilder {
 	if len(params) == 0 {
-		log.Fatal().Msg("WithOneOfParam must contain at least one parameter")
+		return &invalidParam{err: errors.New("WithOneOfParam must contain at least one parameter")}
 	}
 	return &oneOfParam{params: params}
 }
 
+func (p *oneOfParam) validate() error {
+	for _, param := range p.params {
+		if err := validateParam(param); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *oneOfParam) Build() url.Values {
 	index := random.Number(0, int64(len(p.params)-1))
 	return p.params[index].Build()
@@ -78,22 +94,51 @@ type chanceParam struct {
 
 func NewParamWithUsageChange(chance int, param QueryParamBuilder) QueryParamBuilder {
 	if chance > 100 || chance < 0 {
-		log.Fatal().Msg("NewParamWithUsageChange chance value must be between 0 and 100")
+		return &invalidParam{err: errors.New("NewParamWithUsageChange chance value must be between 0 and 100")}
 	}
 	r, err := weightedrand.NewChooser(
 		weightedrand.NewChoice(true, chance),
-		weightedrand.NewChoice(true, 100-chance),
+		weightedrand.NewChoice(false, 100-chance),
 	)
 	if err != nil {
-		log.Fatal().Err(err).Msg("can't create chooser")
+		return &invalidParam{err: fmt.Errorf("can't create chooser: %w", err)}
 	}
 
 	return &chanceParam{chance: chance, param: param, r: r}
 }
 
+func (p *chanceParam) validate() error {
+	return validateParam(p.param)
+}
+
 func (p *chanceParam) Build() url.Values {
 	if p.r.Pick() {
 		return p.param.Build()
 	}
 	return url.Values{}
 }
+
+// invalidParam is returned by constructors which got invalid arguments. The error
+// is reported when the URLBuilder is created.
+type invalidParam struct {
+	err error
+}
+
+func (p *invalidParam) Build() url.Values {
+	return url.Values{}
+}
+
+func (p *invalidParam) validate() error {
+	return p.err
+}
+
+type validator interface {
+	validate() error
+}
+
+func validateParam(param QueryParamBuilder) error {
+	if v, ok := param.(validator); ok {
+		return v.validate()
+	}
+	return nil
+}
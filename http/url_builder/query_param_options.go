@@ -1,8 +1,9 @@
 package url_builder
 
 import (
+	"errors"
+	"fmt"
 	"github.com/mroth/weightedrand/v2"
-	"github.com/rs/zerolog/log"
 	"github.com/scayle/goload/utils/random"
 	"strconv"
 )
@@ -17,14 +18,14 @@ func WithParamName(name string) QueryParameterOption {
 
 func WithParamUsagePercentage(pct int) QueryParameterOption {
 	if pct > 100 || pct < 0 {
-		log.Fatal().Msg("WithParamUsagePercentage pct must be between 0 and 100")
+		return withParamError(errors.New("WithParamUsagePercentage pct must be between 0 and 100"))
 	}
 	r, err := weightedrand.NewChooser(
 		weightedrand.NewChoice(true, pct),
-		weightedrand.NewChoice(true, 100-pct),
+		weightedrand.NewChoice(false, 100-pct),
 	)
 	if err != nil {
-		log.Fatal().Err(err).Msg("can't create chooser")
+		return withParamError(fmt.Errorf("can't create chooser: %w", err))
 	}
 
 	return func(param *QueryParameter) {
@@ -59,7 +60,7 @@ type WeightedValueOpt struct {
 
 func WithWeightedParamValue(opts ...WeightedValueOpt) QueryParameterOption {
 	if len(opts) == 0 {
-		log.Fatal().Msg("WithWeightedParamValue must have at least one option")
+		return withParamError(errors.New("WithWeightedParamValue must have at least one option"))
 	}
 	values := make([]weightedrand.Choice[string, int], 0, len(opts))
 	for _, opt := range opts {
@@ -68,7 +69,7 @@ func WithWeightedParamValue(opts ...WeightedValueOpt) QueryParameterOption {
 
 	r, err := weightedrand.NewChooser(values...)
 	if err != nil {
-		log.Fatal().Err(err).Msg("can't create chooser")
+		return withParamError(fmt.Errorf("can't create chooser: %w", err))
 	}
 
 	return func(param *QueryParameter) {
@@ -80,7 +81,7 @@ func WithWeightedParamValue(opts ...WeightedValueOpt) QueryParameterOption {
 
 func WithOneOfParamValue(values []string) QueryParameterOption {
 	if len(values) == 0 {
-		log.Fatal().Msg("WithOneOfParamValue must have at least one value")
+		return withParamError(errors.New("WithOneOfParamValue must have at least one value"))
 	}
 	return func(param *QueryParameter) {
 		param.Value = func() []string {
@@ -98,3 +99,12 @@ func WithRandomNumberParamValue(min int64, max int64) QueryParameterOption {
 		}
 	}
 }
+
+// withParamError records an invalid option, which is reported when the URLBuilder is created.
+func withParamError(err error) QueryParameterOption {
+	return func(param *QueryParameter) {
+		if param.err == nil {
+			param.err = err
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package url_builder
+
+import "testing"
+
+func TestParamUsage(t *testing.T) {
+	param := func() *QueryParameter {
+		return NewQueryParameter(WithParamName("q"), WithParamValue("v"))
+	}
+
+	for _, chance := range []int{0, 100} {
+		builders := map[string]QueryParamBuilder{
+			"WithParamUsagePercentage": NewQueryParameter(WithParamName("q"), WithParamValue("v"), WithParamUsagePercentage(chance)),
+			"NewParamWithUsageChange":  NewParamWithUsageChange(chance, param()),
+		}
+		for name, builder := range builders {
+			if err := validateParam(builder); err != nil {
+				t.Fatalf("%s(%d): %v", name, chance, err)
+			}
+			for i := 0; i < 100; i++ {
+				if used := builder.Build().Has("q"); used != (chance == 100) {
+					t.Fatalf("%s(%d) used the parameter: %v", name, chance, used)
+				}
+			}
+		}
+	}
+}
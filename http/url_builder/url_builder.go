@@ -1,8 +1,8 @@
 package url_builder
 
 import (
+	"errors"
 	"fmt"
-	"github.com/rs/zerolog/log"
 	"github.com/scayle/goload/utils/random"
 	"net/url"
 	"strings"
@@ -12,10 +12,13 @@ type URLBuilder struct {
 	rawURL                  string
 	urlParameterRandomizers []URLParameterRandomizer
 	queryParams             []QueryParamBuilder
+
+	err error
 }
 
 type URLBuilderOption func(*URLBuilder)
 
+// NewURLBuilder creates a URLBuilder from the given options. Invalid options are reported by Err and Build.
 func NewURLBuilder(opts []URLBuilderOption) *URLBuilder {
 	urlBuilder := URLBuilder{}
 
@@ -24,13 +27,28 @@ func NewURLBuilder(opts []URLBuilderOption) *URLBuilder {
 	}
 
 	if urlBuilder.rawURL == "" {
-		log.Fatal().Msg("NewURLBuilder must include WithRawURL option")
+		urlBuilder.err = errors.New("NewURLBuilder must include WithRawURL option")
+	}
+	for _, param := range urlBuilder.queryParams {
+		if urlBuilder.err != nil {
+			break
+		}
+		urlBuilder.err = validateParam(param)
 	}
 
 	return &urlBuilder
 }
 
+// Err returns the first error of the options the builder was created with.
+func (builder *URLBuilder) Err() error {
+	return builder.err
+}
+
 func (builder *URLBuilder) Build(basePath *string) (*url.URL, error) {
+	if builder.err != nil {
+		return nil, builder.err
+	}
+
 	q := url.Values{}
 
 	for _, param := range builder.queryParams {
@@ -43,21 +61,22 @@ func (builder *URLBuilder) Build(basePath *string) (*url.URL, error) {
 
 	query := q.Encode()
 
+	// the parameters are replaced before joining the base path, which would escape keys like {id}
 	rawURL := builder.rawURL
-	if basePath != nil {
-		var err error
-		rawURL, err = url.JoinPath(*basePath, builder.rawURL)
+	for _, u := range builder.urlParameterRandomizers {
+		v, err := u.GetValue()
 		if err != nil {
 			return nil, err
 		}
+		rawURL = strings.Replace(rawURL, u.key, v, 1)
 	}
 
-	for _, u := range builder.urlParameterRandomizers {
-		v, err := u.GetValue()
+	if basePath != nil {
+		var err error
+		rawURL, err = url.JoinPath(*basePath, rawURL)
 		if err != nil {
 			return nil, err
 		}
-		rawURL = strings.Replace(rawURL, u.key, v, 1)
 	}
 
 	u, err := url.Parse(rawURL)
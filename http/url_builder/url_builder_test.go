@@ -0,0 +1,39 @@
+package url_builder
+
+import "testing"
+
+func TestURLBuilder(t *testing.T) {
+	basePath := "http://localhost/api"
+	builder := NewURLBuilder([]URLBuilderOption{
+		WithRawURL("/items/{id}"),
+		WithURLParam("{id}", []string{"17"}),
+		WithQueryParams(NewQueryParameter(WithParamName("q"), WithParamValue("v"))),
+	})
+	if err := builder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := builder.Build(&basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://localhost/api/items/17?q=v"; u.String() != want {
+		t.Errorf("Build() = %v, want %v", u, want)
+	}
+}
+
+func TestURLBuilderErrors(t *testing.T) {
+	tests := map[string][]URLBuilderOption{
+		"no raw URL":    {WithURLParam("{id}", []string{"17"})},
+		"invalid param": {WithRawURL("/items"), WithQueryParams(NewQueryParameter(WithParamName("q")))},
+	}
+	for name, opts := range tests {
+		builder := NewURLBuilder(opts)
+		if builder.Err() == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, err := builder.Build(nil); err == nil {
+			t.Errorf("%s: expected Build to fail", name)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package goload
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -9,71 +11,205 @@ import (
 	ctx_utils "github.com/scayle/goload/utils/ctx"
 	"math"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type resultHandler func(lt *LoadTest, result *Result)
 
+// Hook is notified about the lifecycle of a load test, e.g. to start and stop servers
+// or to flush buffered output.
+type Hook interface {
+	// Start is called before the first hit is sent. An error prevents the load test from running.
+	Start(lt *LoadTest) error
+	// Finish is called after all results have been handled.
+	Finish(lt *LoadTest, report *Report) error
+}
+
 type LoadTest struct {
 	Pacer     pacer.Pacer
 	Runner    *Runner
 	Executors []Executor
 
 	duration time.Duration
+	logger   zerolog.Logger
 
 	resultHandlers   []resultHandler
+	hooks            []Hook
 	resultAggregator *resultAggregator
+	shares           *executorShares
 	reportInterval   time.Duration
+	timelineInterval time.Duration
+	thresholds       []Threshold
+	abortedBy        atomic.Pointer[ThresholdResult]
+	capacitySearch   *capacitySearch
+	control          *pacer.ControlledPacer
+	coordinator      *coordinator
+	agent            *agent
+
+	timelineMu sync.Mutex
+	timeline   []TimelineSample
 
-	done chan struct{}
+	// the hits expected by the pacer at expectedAt are cached, see expectedHits
+	expectedMu     sync.Mutex
+	expectedAt     time.Duration
+	expectedHitsAt float64
+
+	started atomic.Bool
+	done    chan struct{}
 }
 
 type LoadTestOptions struct {
-	pacer           pacer.Pacer
-	executors       []Executor
-	duration        time.Duration
-	initialWorkers  int
-	maxWorkers      int
-	resultHandlers  []resultHandler
-	weightOverrides map[string]int
-	reportInterval  time.Duration
-	ctxModifier     func(ctx context.Context) context.Context
-	defaultTimeout  time.Duration
+	pacer            pacer.Pacer
+	executors        []Executor
+	duration         time.Duration
+	initialWorkers   int
+	maxWorkers       int
+	resultHandlers   []resultHandler
+	hooks            []Hook
+	weightOverrides  map[string]int
+	reportInterval   time.Duration
+	timelineInterval time.Duration
+	ctxModifier      func(ctx context.Context) context.Context
+	defaultTimeout   time.Duration
+	thresholds       []Threshold
+	userStages       []UserStage
+	thinkTime        ThinkTime
+	capacitySearch   *capacitySearch
+	// rateControl wraps the pacer to change the rate while the load test is running, see WithRateControl
+	rateControl bool
+	// rateFactor scales the rate of the pacer, see the rate-factor override
+	rateFactor float64
+	logger     *zerolog.Logger
+
+	lateTickThreshold time.Duration
+	tickPolicy        TickPolicy
+	workerIdleTimeout time.Duration
+	coordinator       *coordinator
+	agent             *agent
+	// overrides are applied after all other options, see WithOverrides
+	overrides []LoadTestOption
+
+	errs []error
 }
 type LoadTestOption func(*LoadTestOptions)
 
+// RunLoadTest creates and runs a load test with a console logger, unless WithLogger is used, and
+// prints the summary once it has finished. The process exits with a non-zero code if the load test fails.
+// Add WithOverrides(os.Args[1:]) to let flags and environment variables change the options.
+//
+// Use New to embed load tests in other programs.
 func RunLoadTest(opts ...LoadTestOption) {
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
-
-	// set default options
-	options, err := renderAndValidateOptions(opts)
+	console := log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
+	loadTest, err := New(append([]LoadTestOption{WithLogger(console)}, opts...)...)
+	if errors.Is(err, flag.ErrHelp) {
+		// the usage was printed by WithOverrides
+		os.Exit(0)
+	}
 	if err != nil {
 		fmt.Printf("Invalid options: %v\n", err)
 		os.Exit(1)
 	}
 
+	ctx := ctx_utils.ContextWithInterrupt(context.Background())
+
+	report, err := loadTest.Run(ctx)
+	if report != nil {
+		fmt.Println("summary:")
+		if err := report.WriteTable(os.Stdout); err != nil {
+			loadTest.logger.Error().Err(err).Msg("failed to write report")
+		}
+	}
+	if err != nil {
+		fmt.Printf("Load test failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// New creates a load test from the given options. All validation failures are returned as an error.
+func New(opts ...LoadTestOption) (*LoadTest, error) {
+	options, err := renderAndValidateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := log.Logger
+	if options.logger != nil {
+		logger = *options.logger
+	}
+
 	resultAggregator := newResultAggregator()
 	options.resultHandlers = append(options.resultHandlers, resultAggregator.resultAggregationHandler)
 
-	loadTest := &LoadTest{
+	runner := NewRunner(options)
+	if _, err := runner.getExecutorChooser(options.executors); err != nil {
+		return nil, err
+	}
+
+	// The pacer is wrapped to allow changing the rate while the load test is running. The share of
+	// an agent is set once it has joined the coordinator.
+	var control *pacer.ControlledPacer
+	if options.pacer != nil && (options.rateControl || options.agent != nil || options.rateFactor != 1) {
+		control = pacer.NewControlledPacer(options.pacer)
+		control.ScaleBase(options.rateFactor)
+		options.pacer = control
+	}
+
+	return &LoadTest{
 		Pacer:            options.pacer,
-		Runner:           NewRunner(options),
+		Runner:           runner,
 		Executors:        options.executors,
 		duration:         options.duration,
+		logger:           logger,
 		resultHandlers:   options.resultHandlers,
+		hooks:            options.hooks,
 		resultAggregator: resultAggregator,
+		shares:           newExecutorShares(options.executors, runner.weight),
 		reportInterval:   options.reportInterval,
+		timelineInterval: options.timelineInterval,
+		thresholds:       options.thresholds,
+		capacitySearch:   options.capacitySearch,
+		control:          control,
+		coordinator:      options.coordinator,
+		agent:            options.agent,
 		done:             make(chan struct{}),
-	}
+	}, nil
+}
 
-	ctx := ctx_utils.ContextWithInterrupt(context.Background())
+// Run executes the load test and returns a summary of all results once it has finished.
+// A load test can only be run once.
+//
+// If thresholds are defined and at least one of them fails, the report is returned
+// together with an error wrapping ErrThresholdsBreached.
+func (lt *LoadTest) Run(ctx context.Context) (*Report, error) {
+	if !lt.started.CompareAndSwap(false, true) {
+		return nil, errors.New("load test can only be run once")
+	}
 
-	loadTest.Run(ctx)
-}
+	for i, hook := range lt.hooks {
+		if err := hook.Start(lt); err != nil {
+			return nil, errors.Join(err, lt.finishHooks(lt.hooks[:i], lt.Report()))
+		}
+	}
 
-func (lt *LoadTest) Run(ctx context.Context) {
-	resultChan := lt.Runner.Run(ctx, lt.Executors, lt.Pacer, lt.duration)
+	var resultChan <-chan *Result
+	var err error
+	switch {
+	case lt.coordinator != nil:
+		// the hits are sent by the agents
+		resultChan, err = lt.coordinator.run(ctx)
+	case lt.agent != nil:
+		resultChan, err = lt.agent.run(ctx, lt)
+	default:
+		resultChan, err = lt.Runner.Run(ctx, lt.Executors, lt.Pacer, lt.duration)
+	}
+	if err != nil {
+		return nil, errors.Join(err, lt.finishHooks(lt.hooks, lt.Report()))
+	}
 	lt.runReporter(ctx)
+	lt.recordTimeline()
+	lt.watchThresholds()
 
 	for result := range resultChan {
 		for _, handler := range lt.resultHandlers {
@@ -81,20 +217,118 @@ func (lt *LoadTest) Run(ctx context.Context) {
 		}
 	}
 	close(lt.done)
+
+	report := lt.Report()
+	err = report.CheckThresholds(lt.thresholds...)
+	if aborted := lt.abortedBy.Load(); aborted != nil {
+		// the thresholds which failed at the end are still reported
+		err = errors.Join(err, fmt.Errorf("%w: aborted because %s failed (actual: %s)", ErrThresholdsBreached, aborted.Description, aborted.Actual))
+	}
+
+	return report, errors.Join(err, lt.finishHooks(lt.hooks, report))
+}
+
+func (lt *LoadTest) finishHooks(hooks []Hook, report *Report) error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook.Finish(lt, report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Elapsed returns the time since the load test has been started.
+func (lt *LoadTest) Elapsed() time.Duration {
+	startedAt := lt.Runner.startedAt.Load()
+	if startedAt == nil {
+		return 0
+	}
+	return time.Since(*startedAt)
+}
+
+// WorkerStats returns the number of live, busy and idle workers or virtual users. The coordinator
+// of a distributed load test returns the sum over all running agents.
+func (lt *LoadTest) WorkerStats() WorkerStats {
+	if lt.coordinator != nil {
+		return lt.coordinator.workers()
+	}
+	return lt.Runner.WorkerStats()
+}
+
+// Report returns a summary of all results received so far.
+func (lt *LoadTest) Report() *Report {
+	startedAt := lt.Runner.startedAt.Load()
+	if startedAt == nil {
+		return lt.resultAggregator.report(time.Time{}, 0, 0, lt.shares)
+	}
+	duration := time.Since(*startedAt)
+	report := lt.resultAggregator.report(*startedAt, duration, lt.expectedHits(duration), lt.shares)
+
+	lt.timelineMu.Lock()
+	report.Timeline = append([]TimelineSample(nil), lt.timeline...)
+	lt.timelineMu.Unlock()
+
+	report.VirtualUsers = lt.Runner.virtualUserStats()
+	if lt.coordinator != nil {
+		saturation := lt.coordinator.saturation()
+		report.Saturation = &saturation
+	} else if lt.Pacer != nil {
+		saturation := lt.Runner.Saturation()
+		report.Saturation = &saturation
+	}
+	if lt.capacitySearch != nil {
+		report.Capacity = lt.capacitySearch.capacity()
+	}
+
+	return report
+}
+
+// expectedHits returns the hits the pacer expects after the elapsed duration. They are integrated
+// from the previous call, as pacers without an exact value are integrated from the start otherwise.
+func (lt *LoadTest) expectedHits(elapsed time.Duration) float64 {
+	if lt.Pacer == nil {
+		return 0
+	}
+
+	lt.expectedMu.Lock()
+	defer lt.expectedMu.Unlock()
+
+	lt.expectedHitsAt = pacer.ExpectedHitsSince(lt.Pacer, lt.expectedAt, lt.expectedHitsAt, elapsed)
+	lt.expectedAt = elapsed
+	return lt.expectedHitsAt
 }
 
 func (lt *LoadTest) runReporter(ctx context.Context) {
 	go func() {
 		ticker := time.NewTicker(lt.reportInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case <-lt.done:
+				return
 			case <-ticker.C:
-				fmt.Printf("expected pace: %.2f/s\n", lt.Pacer.Rate(time.Now().Sub(*lt.Runner.startedAt)))
+				if lt.Pacer != nil {
+					fmt.Printf("expected pace: %.2f/s\n", lt.Pacer.Rate(lt.Elapsed()))
+				} else {
+					fmt.Printf("virtual users: %d\n", lt.Runner.Workers())
+				}
+				workers := lt.WorkerStats()
+				fmt.Printf("workers: %d live, %d busy, %d idle\n", workers.Live, workers.Busy, workers.Idle)
 				fmt.Printf("actual pace: %.2f/s\n", float64(lt.resultAggregator.rateCounter.Rate())/10)
 				fmt.Printf("total hits: %d\n", lt.resultAggregator.total.Load())
 				fmt.Printf("total failures: %d\n", lt.resultAggregator.failures.Load())
+				if saturation := lt.Runner.Saturation(); saturation.Saturated() {
+					fmt.Printf("WARNING: %s\n", formatSaturation(&saturation))
+				}
+
+				report := lt.Report()
+				fmt.Printf("latency: %s\n", formatLatencies(report.Total))
+				for _, stats := range report.Executors {
+					fmt.Printf("  %s (%d hits, %d failures): %s\n", stats.Name, stats.Requests, stats.Failures, formatLatencies(stats))
+				}
 			}
 		}
 	}()
@@ -104,22 +338,51 @@ var defaultResultHandlers []resultHandler
 
 func renderAndValidateOptions(opts []LoadTestOption) (LoadTestOptions, error) {
 	options := LoadTestOptions{
-		pacer:           nil,
-		executors:       nil,
-		duration:        0,
-		initialWorkers:  10,
-		maxWorkers:      math.MaxInt,
-		resultHandlers:  defaultResultHandlers,
-		weightOverrides: nil,
-		reportInterval:  10 * time.Second,
+		pacer:             nil,
+		executors:         nil,
+		duration:          0,
+		initialWorkers:    10,
+		maxWorkers:        math.MaxInt,
+		resultHandlers:    defaultResultHandlers,
+		weightOverrides:   nil,
+		reportInterval:    10 * time.Second,
+		timelineInterval:  time.Second,
+		lateTickThreshold: defaultLateTickThreshold,
+		tickPolicy:        TickPolicyDelay,
+		workerIdleTimeout: defaultWorkerIdleTimeout,
+		rateFactor:        1,
 	}
 
 	for _, opt := range opts {
 		opt(&options)
 	}
+	for _, opt := range options.overrides {
+		opt(&options)
+	}
 
-	if options.pacer == nil {
-		return LoadTestOptions{}, fmt.Errorf("pacer is required")
+	for _, threshold := range options.thresholds {
+		options.errs = append(options.errs, threshold.err)
+	}
+	if err := errors.Join(options.errs...); err != nil {
+		return LoadTestOptions{}, err
+	}
+	if search := options.capacitySearch; search != nil {
+		if options.pacer != pacer.Pacer(search.pacer) || len(options.userStages) > 0 {
+			return LoadTestOptions{}, fmt.Errorf("capacity search can't be combined with another pacer or virtual users")
+		}
+		if err := search.validate(); err != nil {
+			return LoadTestOptions{}, err
+		}
+	}
+	if len(options.userStages) > 0 {
+		if options.pacer != nil {
+			return LoadTestOptions{}, fmt.Errorf("a pacer can't be combined with virtual users")
+		}
+		if err := validateUserStages(options.userStages); err != nil {
+			return LoadTestOptions{}, err
+		}
+	} else if options.pacer == nil {
+		return LoadTestOptions{}, fmt.Errorf("pacer or virtual users are required")
 	}
 	if len(options.executors) == 0 {
 		return LoadTestOptions{}, fmt.Errorf("should define at least one executor")
@@ -127,6 +390,19 @@ func renderAndValidateOptions(opts []LoadTestOption) (LoadTestOptions, error) {
 	if options.initialWorkers == 0 || options.maxWorkers == 0 {
 		return LoadTestOptions{}, fmt.Errorf("inital and max workers must be > 0")
 	}
+	if err := validateDistribution(options); err != nil {
+		return LoadTestOptions{}, err
+	}
+	if options.pacer != nil {
+		if err := pacer.Validate(options.pacer); err != nil {
+			return LoadTestOptions{}, fmt.Errorf("invalid pacer: %w", err)
+		}
+	}
+	for _, ex := range options.executors {
+		if err := validateExecutor(ex); err != nil {
+			return LoadTestOptions{}, err
+		}
+	}
 
 	return options, nil
 }
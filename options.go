@@ -2,6 +2,8 @@ package goload
 
 import (
 	"context"
+	"fmt"
+	"github.com/rs/zerolog"
 	"github.com/scayle/goload/pacer"
 	"time"
 )
@@ -30,12 +32,84 @@ func WithConstantPacer(rate pacer.Rate) LoadTestOption {
 	}
 }
 
+// WithStepPacer goes through the given steps in order, see pacer.NewStepPacer.
+func WithStepPacer(rampDuration time.Duration, steps ...pacer.Step) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.pacer = pacer.NewStepPacer(steps, rampDuration)
+	}
+}
+
+// WithSinePacer lets the rate oscillate around the mean, see pacer.NewSinePacer.
+func WithSinePacer(mean, amplitude pacer.Rate, period, phase time.Duration) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.pacer = pacer.NewSinePacer(mean, amplitude, period, phase)
+	}
+}
+
+// WithPoissonPacer sends hits with random intervals averaging the rate, see pacer.NewPoissonPacer.
+func WithPoissonPacer(rate pacer.Rate, seed int64) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.pacer = pacer.NewPoissonPacer(rate, seed)
+	}
+}
+
+// WithTracePacer replays the arrival pattern recorded in the trace file at path, see pacer.NewTracePacer.
+func WithTracePacer(path string, format pacer.TraceFormat, speed float64, loop bool) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		trace, err := pacer.ReadTraceFile(path, format)
+		if err != nil {
+			options.errs = append(options.errs, fmt.Errorf("can't read trace: %w", err))
+			return
+		}
+		options.pacer = pacer.NewTracePacer(trace, speed, loop)
+	}
+}
+
+// WithStages runs the pacers of the given stages one after another, see pacer.NewStagedPacer.
+func WithStages(stages ...pacer.Stage) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.pacer = pacer.NewStagedPacer(stages...)
+	}
+}
+
+// WithPacer uses a custom pacer implementation.
+func WithPacer(p pacer.Pacer) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.pacer = p
+	}
+}
+
+// WithVirtualUsers uses the closed model instead of a pacer: each virtual user sends a hit, waits for the
+// think time and starts over. The number of virtual users follows the given stages starting at zero and
+// keeps the target of the last stage afterward. Without a duration, the load test ends once the last stage
+// has reduced the users to zero.
+func WithVirtualUsers(stages ...UserStage) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.userStages = append(options.userStages, stages...)
+	}
+}
+
+// WithThinkTime sets the pause of virtual users between two iterations, e.g. ExponentialThinkTime.
+func WithThinkTime(thinkTime ThinkTime) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.thinkTime = thinkTime
+	}
+}
+
 func WithReportInterval(reportInterval time.Duration) LoadTestOption {
 	return func(options *LoadTestOptions) {
 		options.reportInterval = reportInterval
 	}
 }
 
+// WithTimelineInterval sets the interval in which results are sampled for the timeline of the report.
+// A zero interval disables the timeline.
+func WithTimelineInterval(interval time.Duration) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.timelineInterval = interval
+	}
+}
+
 func WithInitialWorkerCount(count int) LoadTestOption {
 	return func(options *LoadTestOptions) {
 		options.initialWorkers = count
@@ -48,18 +122,40 @@ func WithMaxWorkerCount(count int) LoadTestOption {
 	}
 }
 
+// WithWorkerIdleTimeout stops workers which haven't sent a hit for the given duration, so the worker
+// count shrinks again after a spike. The initial workers are kept. Defaults to 30s, zero keeps all workers.
+func WithWorkerIdleTimeout(timeout time.Duration) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.workerIdleTimeout = timeout
+	}
+}
+
 func WithAdditionalResultHandler(handler resultHandler) LoadTestOption {
 	return func(options *LoadTestOptions) {
 		options.resultHandlers = append(options.resultHandlers, handler)
 	}
 }
 
+// WithHook registers a Hook which is notified when the load test starts and finishes.
+func WithHook(hook Hook) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.hooks = append(options.hooks, hook)
+	}
+}
+
 func WithContextModifier(fn func(ctx context.Context) context.Context) LoadTestOption {
 	return func(options *LoadTestOptions) {
 		options.ctxModifier = fn
 	}
 }
 
+// WithLogger sets the logger the load test logs to instead of the global zerolog logger.
+func WithLogger(logger zerolog.Logger) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.logger = &logger
+	}
+}
+
 func WithDefaultTimeout(timeout time.Duration) LoadTestOption {
 	return func(options *LoadTestOptions) {
 		options.defaultTimeout = timeout
@@ -71,3 +167,24 @@ func WithWeightOverrides(overrides map[string]int) LoadTestOption {
 		options.weightOverrides = overrides
 	}
 }
+
+// WithThresholds adds pass/fail criteria which are checked against the results of the load test.
+func WithThresholds(thresholds ...Threshold) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.thresholds = append(options.thresholds, thresholds...)
+	}
+}
+
+// WithReportFile stores the final report as JSON at path, e.g. to compare it with later runs.
+func WithReportFile(path string) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.hooks = append(options.hooks, reportFileWriter{path: path})
+	}
+}
+
+// WithHTMLReport stores the final report as a self-contained HTML page at path.
+func WithHTMLReport(path string) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		options.hooks = append(options.hooks, htmlReportWriter{path: path})
+	}
+}
@@ -0,0 +1,210 @@
+package goload
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/scayle/goload/pacer"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// overrideEnvPrefix is prepended to the upper-cased name of an override to get its environment variable.
+const overrideEnvPrefix = "GOLOAD_"
+
+// override is a flag which replaces an option of the load test.
+type override struct {
+	name  string
+	usage string
+	apply func(value string) (LoadTestOption, error)
+}
+
+var overrides = []override{
+	{
+		name:  "duration",
+		usage: "duration of the load test, e.g. 10m",
+		apply: func(value string) (LoadTestOption, error) {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			return WithDuration(duration), nil
+		},
+	},
+	{
+		name:  "pacer",
+		usage: "pacer replacing the configured one, e.g. 10/s or ramp:1/s,50/s,2m, see pacer.ParsePacer",
+		apply: func(value string) (LoadTestOption, error) {
+			p, err := pacer.ParsePacer(value)
+			if err != nil {
+				return nil, err
+			}
+			return WithPacer(p), nil
+		},
+	},
+	{
+		name:  "rate-factor",
+		usage: "factor the rate of the pacer is multiplied with, e.g. 0.1 for a smoke test",
+		apply: func(value string) (LoadTestOption, error) {
+			factor, err := strconv.ParseFloat(value, 64)
+			if err != nil || factor < 0 {
+				return nil, fmt.Errorf("invalid factor %q", value)
+			}
+			return func(options *LoadTestOptions) {
+				if options.pacer == nil {
+					options.errs = append(options.errs, errors.New("rate factor needs a pacer"))
+					return
+				}
+				options.rateFactor = factor
+			}, nil
+		},
+	},
+	{
+		name:  "workers",
+		usage: "initial number of workers",
+		apply: func(value string) (LoadTestOption, error) {
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			return WithInitialWorkerCount(count), nil
+		},
+	},
+	{
+		name:  "max-workers",
+		usage: "maximum number of workers",
+		apply: func(value string) (LoadTestOption, error) {
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			return WithMaxWorkerCount(count), nil
+		},
+	},
+	{
+		name:  "report-interval",
+		usage: "interval of the intermediate reports, e.g. 30s",
+		apply: func(value string) (LoadTestOption, error) {
+			interval, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			return WithReportInterval(interval), nil
+		},
+	},
+	{
+		name:  "weights",
+		usage: "weights of executors by name, e.g. search=3,checkout=1, merged with WithWeightOverrides",
+		apply: func(value string) (LoadTestOption, error) {
+			weights := map[string]int{}
+			for _, pair := range strings.Split(value, ",") {
+				name, weight, found := strings.Cut(pair, "=")
+				if !found {
+					return nil, fmt.Errorf("invalid weight %q, expected name=weight", pair)
+				}
+				w, err := strconv.Atoi(strings.TrimSpace(weight))
+				if err != nil || w < 0 {
+					return nil, fmt.Errorf("invalid weight %q, expected name=weight", pair)
+				}
+				weights[strings.TrimSpace(name)] = w
+			}
+			return func(options *LoadTestOptions) {
+				known := map[string]bool{}
+				for _, executor := range options.executors {
+					known[executor.Name()] = true
+				}
+				var unknown []string
+				for name := range weights {
+					if !known[name] {
+						unknown = append(unknown, strconv.Quote(name))
+					}
+				}
+				if len(unknown) > 0 {
+					slices.Sort(unknown)
+					options.errs = append(options.errs, fmt.Errorf("invalid override -weights: unknown executors %s", strings.Join(unknown, ", ")))
+					return
+				}
+
+				merged := maps.Clone(options.weightOverrides)
+				if merged == nil {
+					merged = map[string]int{}
+				}
+				maps.Copy(merged, weights)
+				options.weightOverrides = merged
+			}, nil
+		},
+	},
+}
+
+// RegisterOverride adds a flag which can be used with WithOverrides, e.g. for options of executor packages.
+// It has to be called before WithOverrides, usually at the start of main.
+func RegisterOverride(name string, usage string, apply func(value string) (LoadTestOption, error)) {
+	overrides = append(overrides, override{name: name, usage: usage, apply: apply})
+}
+
+// WithOverrides lets flags in args, usually os.Args[1:], and environment variables override the options
+// of the load test, so one binary can run e.g. smoke, load and soak profiles. Each flag can also be set
+// with the environment variable GOLOAD_<NAME>, e.g. GOLOAD_MAX_WORKERS for -max-workers. Flags take
+// precedence over environment variables and both over the other options, regardless of their order.
+//
+// -h prints the supported flags and makes New return an error wrapping flag.ErrHelp, which RunLoadTest
+// exits on without a failure.
+func WithOverrides(args []string) LoadTestOption {
+	opts, err := parseOverrides(args)
+	return func(options *LoadTestOptions) {
+		if err != nil {
+			options.errs = append(options.errs, err)
+			return
+		}
+		options.overrides = append(options.overrides, opts...)
+	}
+}
+
+func parseOverrides(args []string) ([]LoadTestOption, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	values := make([]*string, len(overrides))
+	for i, o := range overrides {
+		values[i] = fs.String(o.name, "", fmt.Sprintf("%s (env %s)", o.usage, overrideEnv(o.name)))
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid overrides: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var opts []LoadTestOption
+	for i, o := range overrides {
+		value := *values[i]
+		if !set[o.name] {
+			value = os.Getenv(overrideEnv(o.name))
+		}
+		if value == "" {
+			continue
+		}
+
+		opt, err := o.apply(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid override -%s: %w", o.name, err)
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
+
+func overrideEnv(name string) string {
+	return overrideEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
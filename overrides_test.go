@@ -0,0 +1,118 @@
+package goload
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/scayle/goload/pacer"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func overrideTestOptions(overrides ...string) []LoadTestOption {
+	noop := func(ctx context.Context) error { return nil }
+	return []LoadTestOption{
+		WithDuration(time.Minute),
+		WithConstantPacer(pacer.Rate{Freq: 10, Per: time.Second}),
+		WithExecutors(NewGenericExecutor("search", noop), NewGenericExecutor("checkout", noop)),
+		WithOverrides(overrides),
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	options, err := renderAndValidateOptions(overrideTestOptions(
+		"-duration", "5s",
+		"-workers", "3",
+		"-max-workers", "7",
+		"-pacer", "ramp:1/s,50/s,2m",
+		"-report-interval", "1s",
+		"-rate-factor", "0.5",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.duration != 5*time.Second {
+		t.Errorf("duration = %v, want 5s", options.duration)
+	}
+	if options.initialWorkers != 3 || options.maxWorkers != 7 {
+		t.Errorf("workers = %d/%d, want 3/7", options.initialWorkers, options.maxWorkers)
+	}
+	if rate := options.pacer.Rate(2 * time.Minute); rate != 50 {
+		t.Errorf("the rate of the pacer is %v, want 50", rate)
+	}
+	if options.reportInterval != time.Second {
+		t.Errorf("report interval = %v, want 1s", options.reportInterval)
+	}
+	if options.rateFactor != 0.5 {
+		t.Errorf("rate factor = %v, want 0.5", options.rateFactor)
+	}
+}
+
+func TestOverridesEnv(t *testing.T) {
+	t.Setenv("GOLOAD_DURATION", "2s")
+	t.Setenv("GOLOAD_MAX_WORKERS", "4")
+
+	tests := []struct {
+		name       string
+		args       []string
+		duration   time.Duration
+		maxWorkers int
+	}{
+		// environment variables take precedence over the options
+		{name: "env", duration: 2 * time.Second, maxWorkers: 4},
+		// flags take precedence over environment variables
+		{name: "flag", args: []string{"-duration", "3s"}, duration: 3 * time.Second, maxWorkers: 4},
+	}
+	for _, tt := range tests {
+		options, err := renderAndValidateOptions(overrideTestOptions(tt.args...))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if options.duration != tt.duration || options.maxWorkers != tt.maxWorkers {
+			t.Errorf("%s: duration = %v and max workers = %d, want %v and %d", tt.name, options.duration, options.maxWorkers, tt.duration, tt.maxWorkers)
+		}
+	}
+}
+
+func TestOverridesWeights(t *testing.T) {
+	opts := append([]LoadTestOption{WithWeightOverrides(map[string]int{"search": 1, "checkout": 1})}, overrideTestOptions("-weights", "search=3")...)
+	options, err := renderAndValidateOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the weights are merged with WithWeightOverrides
+	if want := map[string]int{"search": 3, "checkout": 1}; !maps.Equal(options.weightOverrides, want) {
+		t.Errorf("weights = %v, want %v", options.weightOverrides, want)
+	}
+
+	_, err = renderAndValidateOptions(overrideTestOptions("-weights", "search=3,nope=1,other=2"))
+	if want := `unknown executors "nope", "other"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestOverridesErrors(t *testing.T) {
+	tests := map[string][]string{
+		"invalid override -duration":    {"-duration", "soon"},
+		"invalid override -workers":     {"-workers", "many"},
+		"invalid override -pacer":       {"-pacer", "burst:10/s"},
+		"invalid override -rate-factor": {"-rate-factor", "-1"},
+		"invalid override -weights":     {"-weights", "search"},
+		"invalid overrides":             {"-unknown", "1"},
+		"unexpected arguments: extra":   {"-duration", "1s", "extra"},
+	}
+	for want, args := range tests {
+		_, err := renderAndValidateOptions(overrideTestOptions(args...))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: error = %v, want %q", args, err, want)
+		}
+	}
+
+	_, err := renderAndValidateOptions(overrideTestOptions("-h"))
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: error = %v, want %v", err, flag.ErrHelp)
+	}
+}
@@ -0,0 +1,105 @@
+package pacer
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// AdjustablePacer is a pacer with a constant rate which can be changed while the execution is running.
+// Hits which were expected before a change aren't affected by it. Only the latest change is kept, so
+// the rate before it isn't known anymore and the expected hits before it are interpolated.
+type AdjustablePacer struct {
+	initial Rate
+
+	mu sync.RWMutex
+	// current contains the rate in hits per second starting at the offset of the latest change
+	current rateChange
+}
+
+type rateChange struct {
+	offset time.Duration
+	rate   float64
+	// hits is the number of expected hits at the offset
+	hits float64
+}
+
+// NewAdjustablePacer creates an AdjustablePacer starting at the given rate.
+func NewAdjustablePacer(rate Rate) *AdjustablePacer {
+	return &AdjustablePacer{
+		initial: rate,
+		current: rateChange{rate: rate.hitsPerSec()},
+	}
+}
+
+// SetRate changes the rate to the given hits per second from the elapsed duration of the execution on.
+func (p *AdjustablePacer) SetRate(elapsed time.Duration, hitsPerSec float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elapsed = max(elapsed, p.current.offset)
+	p.current = rateChange{
+		offset: elapsed,
+		rate:   max(hitsPerSec, 0),
+		hits:   p.current.expectedHits(elapsed),
+	}
+}
+
+func (p *AdjustablePacer) validate() error {
+	if err := p.initial.validate(); err != nil {
+		return err
+	}
+	if math.IsInf(p.initial.hitsPerSec(), 0) || math.IsNaN(p.initial.hitsPerSec()) {
+		return errors.New("rate needs a time unit")
+	}
+	return nil
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p *AdjustablePacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	change := p.latest()
+	expectedHits := change.expectedHits(elapsed)
+	if hits == 0 || hits < uint64(expectedHits) {
+		// Running behind, send next hit immediately.
+		return 0
+	}
+	if change.rate <= 0 {
+		// no hits until the rate is changed again
+		return math.MaxInt64 - elapsed
+	}
+
+	wait := (float64(hits+1) - expectedHits) / change.rate * 1e9
+	if wait >= float64(math.MaxInt64-elapsed) {
+		return math.MaxInt64 - elapsed
+	}
+	return time.Duration(wait)
+}
+
+func (p *AdjustablePacer) Rate(elapsed time.Duration) float64 {
+	return p.latest().rate
+}
+
+func (p *AdjustablePacer) expectedHits(t time.Duration) float64 {
+	if t < 0 {
+		return 0
+	}
+
+	return p.latest().expectedHits(t)
+}
+
+// latest returns the latest change.
+func (p *AdjustablePacer) latest() rateChange {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.current
+}
+
+func (c rateChange) expectedHits(t time.Duration) float64 {
+	if t < c.offset {
+		// the hits before the change are spread evenly
+		return c.hits * t.Seconds() / c.offset.Seconds()
+	}
+	return c.hits + c.rate*(t-c.offset).Seconds()
+}
@@ -0,0 +1,67 @@
+package pacer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdjustablePacer(t *testing.T) {
+	p := NewAdjustablePacer(perSec(10))
+	if err := Validate(p); err != nil {
+		t.Fatal(err)
+	}
+
+	testHits(t, p, []hitsTest{{elapsed: 500 * time.Millisecond, hits: 5, rate: 10}})
+	testPace(t, p, []paceTest{
+		{elapsed: 0, hits: 0, want: 0},
+		{elapsed: 900 * time.Millisecond, hits: 9, want: 100 * time.Millisecond},
+	})
+
+	p.SetRate(time.Second, 20)
+	testHits(t, p, []hitsTest{
+		{elapsed: time.Second, hits: 10, rate: 20},
+		{elapsed: 1500 * time.Millisecond, hits: 20, rate: 20},
+	})
+	testPace(t, p, []paceTest{{elapsed: time.Second, hits: 10, want: 50 * time.Millisecond}})
+
+	p.SetRate(2*time.Second, 0)
+	testHits(t, p, []hitsTest{
+		{elapsed: 2 * time.Second, hits: 30, rate: 0},
+		{elapsed: 2500 * time.Millisecond, hits: 30, rate: 0},
+	})
+	testPace(t, p, []paceTest{
+		{elapsed: 2 * time.Second, hits: 20, want: 0},
+		// no hits are expected until the rate is changed again
+		{elapsed: 2 * time.Second, hits: 30, want: never},
+	})
+
+	p.SetRate(3*time.Second, 10)
+	// changes in the past take effect from the latest change on
+	p.SetRate(time.Second, 5)
+	testHits(t, p, []hitsTest{
+		{elapsed: 3 * time.Second, hits: 30, rate: 5},
+		{elapsed: 4 * time.Second, hits: 35, rate: 5},
+	})
+	testPace(t, p, []paceTest{{elapsed: 3 * time.Second, hits: 30, want: 200 * time.Millisecond}})
+}
+
+func TestAdjustablePacerManyChanges(t *testing.T) {
+	p := NewAdjustablePacer(perSec(10))
+	// the expected hits only depend on the current rate and the hits at the latest change
+	for i := 1; i <= 10000; i++ {
+		p.SetRate(time.Duration(i)*time.Millisecond, float64(10+i%2*10))
+	}
+
+	// 5000 milliseconds each at 10/s and 20/s after the first 1ms at 10/s
+	testHits(t, p, []hitsTest{
+		{elapsed: 10 * time.Second, hits: 150, rate: 10},
+		{elapsed: 11 * time.Second, hits: 160, rate: 10},
+	})
+}
+
+func TestAdjustablePacerNegativeRate(t *testing.T) {
+	p := NewAdjustablePacer(perSec(10))
+	p.SetRate(time.Second, -10)
+
+	testHits(t, p, []hitsTest{{elapsed: 2 * time.Second, hits: 10, rate: 0}})
+}
@@ -36,6 +36,14 @@ func (p constantPacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
 	return wait
 }
 
+func (p constantPacer) validate() error {
+	return p.rate.validate()
+}
+
+func (p constantPacer) finished(_ uint64) bool {
+	return p.rate.hitsPerSec() <= 0
+}
+
 func (p constantPacer) Rate(elapsed time.Duration) float64 {
 	return p.rate.hitsPerSec()
 }
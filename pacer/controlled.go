@@ -0,0 +1,366 @@
+package pacer
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ControlledPacer wraps a pacer whose schedule can be changed while the execution is running:
+// the rate can be scaled, replaced by a fixed rate or paused. Hits which were expected before
+// a change aren't affected by it. A scaled pacer keeps the pattern of the wrapped pacer, e.g. the
+// intervals of a poisson or trace pacer, only the time between the hits is stretched.
+//
+// Only the control state of the latest change is kept, so the state before it isn't known anymore
+// and the expected hits before it are interpolated.
+type ControlledPacer struct {
+	pacer Pacer
+
+	mu sync.RWMutex
+	// base is the scale which Reset goes back to, see ScaleBase
+	base float64
+	// current contains the control state starting at the offset of the latest change
+	current controlSegment
+	changed bool
+}
+
+type controlSegment struct {
+	offset time.Duration
+	// hits and pacerHits are the expected hits of the controlled and the wrapped pacer at the offset
+	hits      float64
+	pacerHits float64
+	// index and pacerIndex are the same on the schedule of a wrapped scheduler, see scheduleHits
+	index      float64
+	pacerIndex float64
+
+	scale  float64
+	fixed  bool
+	rate   float64
+	paused bool
+}
+
+// ControlState is the current control state of a ControlledPacer.
+type ControlState struct {
+	// Scale is the factor applied to the rate of the wrapped pacer, unless a fixed rate is set.
+	Scale float64 `json:"scale"`
+	// FixedRate is the rate in hits per second which replaces the wrapped pacer, zero if it isn't set.
+	FixedRate float64 `json:"fixed_rate,omitempty"`
+	Paused    bool    `json:"paused"`
+}
+
+// NewControlledPacer wraps the given pacer. As long as it isn't changed, the schedule of the wrapped pacer is used as is.
+func NewControlledPacer(p Pacer) *ControlledPacer {
+	return &ControlledPacer{
+		pacer:   p,
+		base:    1,
+		current: controlSegment{scale: 1},
+	}
+}
+
+// Pacer returns the wrapped pacer.
+func (p *ControlledPacer) Pacer() Pacer {
+	return p.pacer
+}
+
+// SetRate replaces the wrapped pacer by a fixed rate in hits per second from the elapsed duration on.
+func (p *ControlledPacer) SetRate(elapsed time.Duration, hitsPerSec float64) {
+	p.change(elapsed, func(s *controlSegment) {
+		s.fixed = true
+		s.rate = max(hitsPerSec, 0)
+	})
+}
+
+// Scale multiplies the current rate by the given factor from the elapsed duration on.
+func (p *ControlledPacer) Scale(elapsed time.Duration, factor float64) {
+	factor = max(factor, 0)
+	p.change(elapsed, func(s *controlSegment) {
+		if s.fixed {
+			s.rate *= factor
+		} else {
+			s.scale *= factor
+		}
+	})
+}
+
+// ScaleBase multiplies the rate of the wrapped pacer by the given factor, which Reset goes back to,
+// e.g. for the share of an agent. It has to be called before the execution starts.
+func (p *ControlledPacer) ScaleBase(factor float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	factor = max(factor, 0)
+	p.base *= factor
+	p.current.scale *= factor
+}
+
+// Pause stops sending hits from the elapsed duration on until Resume is called.
+func (p *ControlledPacer) Pause(elapsed time.Duration) {
+	p.change(elapsed, func(s *controlSegment) {
+		s.paused = true
+	})
+}
+
+// Resume continues sending hits after Pause.
+func (p *ControlledPacer) Resume(elapsed time.Duration) {
+	p.change(elapsed, func(s *controlSegment) {
+		s.paused = false
+	})
+}
+
+// Reset goes back to the unscaled rate of the wrapped pacer.
+func (p *ControlledPacer) Reset(elapsed time.Duration) {
+	p.change(elapsed, func(s *controlSegment) {
+		*s = controlSegment{scale: p.base}
+	})
+}
+
+// State returns the control state at the elapsed duration, which is the latest one if the elapsed
+// duration is before the latest change. The scale is relative to the base scale.
+func (p *ControlledPacer) State(_ time.Duration) ControlState {
+	p.mu.RLock()
+	s, base := p.current, p.base
+	p.mu.RUnlock()
+
+	state := ControlState{
+		Scale:  s.scale,
+		Paused: s.paused,
+	}
+	if base > 0 {
+		state.Scale /= base
+	}
+	if s.fixed {
+		state.FixedRate = s.rate
+	}
+	return state
+}
+
+func (p *ControlledPacer) change(elapsed time.Duration, fn func(s *controlSegment)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last := p.current
+	elapsed = max(elapsed, last.offset)
+
+	next := last
+	fn(&next)
+	next.offset = elapsed
+	next.pacerHits = ExpectedHits(p.pacer, elapsed)
+	next.hits = last.advance(elapsed, last.hits, last.pacerHits, next.pacerHits)
+	if s, ok := p.pacer.(scheduler); ok {
+		next.pacerIndex = scheduleHits(s, elapsed, next.pacerHits)
+		next.index = last.advance(elapsed, last.index, last.pacerIndex, next.pacerIndex)
+	}
+	p.current = next
+	p.changed = true
+}
+
+func (p *ControlledPacer) validate() error {
+	return Validate(p.pacer)
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p *ControlledPacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	if p.unchanged() || hits == 0 {
+		// the wrapped pacer also learns about a new execution
+		return p.pacer.Pace(elapsed, hits)
+	}
+
+	next := p.hitAt(float64(hits + 1))
+	if next == math.MaxInt64 {
+		return math.MaxInt64 - elapsed
+	}
+	return max(next-elapsed, 0)
+}
+
+func (p *ControlledPacer) nextHitAt(hits uint64) time.Duration {
+	if p.unchanged() {
+		return NextHitAt(p.pacer, hits, 0)
+	}
+	if hits == 0 {
+		return 0
+	}
+	return p.hitAt(float64(hits + 1))
+}
+
+func (p *ControlledPacer) finished(hits uint64) bool {
+	if p.unchanged() {
+		return Finished(p.pacer, hits)
+	}
+
+	s := p.latest()
+	if s.paused || s.fixed || s.scale <= 0 {
+		// hits may be sent again after the next change
+		return false
+	}
+	_, scheduled := p.pacer.(scheduler)
+	from, pacerFrom := s.start(scheduled)
+	if hits+1 <= uint64(from) {
+		return false
+	}
+	// the number of hits of the wrapped pacer after which the next hit would be sent
+	pacerHits := pacerFrom + (float64(hits+1)-from)/s.scale
+	return Finished(p.pacer, uint64(max(math.Ceil(pacerHits)-1, 0)))
+}
+
+// unchanged returns true if the schedule of the wrapped pacer is used as is.
+func (p *ControlledPacer) unchanged() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return !p.changed && p.current.scale == 1
+}
+
+// hitAt returns the offset at which the given number of hits is expected, math.MaxInt64 if never.
+func (p *ControlledPacer) hitAt(hits float64) time.Duration {
+	// The hits of a wrapped scheduler are counted on its schedule, so the pattern of its hits is kept.
+	sched, ok := p.pacer.(scheduler)
+
+	s := p.latest()
+	from, pacerFrom := s.start(ok)
+
+	switch {
+	case hits <= from:
+		// the hit was due before the latest change
+		return s.offset
+	case s.paused:
+		// no hits until the execution is resumed
+		return math.MaxInt64
+	case s.fixed:
+		if s.rate <= 0 {
+			return math.MaxInt64
+		}
+		return s.offset + time.Duration((hits-from)/s.rate*1e9)
+	case s.scale <= 0:
+		return math.MaxInt64
+	}
+
+	// the hits of the segment are the hits of the wrapped pacer multiplied by the scale
+	pacerHits := pacerFrom + (hits-from)/s.scale
+	if !ok {
+		return offsetOf(p.pacer, pacerHits, s.offset)
+	}
+	next := scheduleOffset(sched, pacerHits)
+	if next == math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return max(next, s.offset)
+}
+
+func (p *ControlledPacer) Rate(elapsed time.Duration) float64 {
+	s := p.latest()
+	switch {
+	case s.paused:
+		return 0
+	case s.fixed:
+		return s.rate
+	default:
+		return s.scale * p.pacer.Rate(elapsed)
+	}
+}
+
+func (p *ControlledPacer) expectedHits(t time.Duration) float64 {
+	if t < 0 {
+		return 0
+	}
+
+	return p.latest().expectedHits(p.pacer, t)
+}
+
+// latest returns the control state of the latest change.
+func (p *ControlledPacer) latest() controlSegment {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.current
+}
+
+func (s controlSegment) expectedHits(p Pacer, t time.Duration) float64 {
+	if t < s.offset {
+		// the hits before the change are spread like the hits of the wrapped pacer
+		if s.pacerHits > 0 {
+			return s.hits * ExpectedHits(p, t) / s.pacerHits
+		}
+		return s.hits * t.Seconds() / s.offset.Seconds()
+	}
+	return s.advance(t, s.hits, s.pacerHits, ExpectedHits(p, t))
+}
+
+// start returns the hits of the controlled and the wrapped pacer at the offset, on the schedule if scheduled.
+func (s controlSegment) start(scheduled bool) (float64, float64) {
+	if scheduled {
+		return s.index, s.pacerIndex
+	}
+	return s.hits, s.pacerHits
+}
+
+// advance returns the hits at t given the hits of the wrapped pacer at t, counted like from and pacerFrom
+// at the offset of the segment.
+func (s controlSegment) advance(t time.Duration, from float64, pacerFrom float64, pacerHits float64) float64 {
+	switch {
+	case s.paused:
+		return from
+	case s.fixed:
+		return from + s.rate*(t-s.offset).Seconds()
+	default:
+		return from + s.scale*(pacerHits-pacerFrom)
+	}
+}
+
+// The hits of a scheduler are mapped onto a continuous schedule: the hit after n hits is sent at
+// n+1 hits of the schedule, in between two hits the schedule is interpolated. Like for the expected
+// hits, the schedule starts at 0 hits.
+
+// scheduleAnchor returns the hits of the schedule at which the hit after n hits is sent and its offset.
+func scheduleAnchor(s scheduler, n uint64) (float64, time.Duration) {
+	if n == 0 {
+		return 0, 0
+	}
+	return float64(n + 1), s.nextHitAt(n)
+}
+
+// scheduleOffset returns the offset at which the schedule reaches the given hits, math.MaxInt64 if never.
+func scheduleOffset(s scheduler, hits float64) time.Duration {
+	if hits <= 0 {
+		return 0
+	}
+
+	n := uint64(max(math.Floor(hits-1), 0))
+	fromHits, from := scheduleAnchor(s, n)
+	if hits == fromHits || from == math.MaxInt64 {
+		return from
+	}
+	toHits, to := scheduleAnchor(s, n+1)
+	if to == math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return from + time.Duration((hits-fromHits)/(toHits-fromHits)*float64(to-from))
+}
+
+// scheduleHits returns the hits of the schedule at the offset. The search starts at the estimate.
+func scheduleHits(s scheduler, offset time.Duration, estimate float64) float64 {
+	if offset <= 0 {
+		return 0
+	}
+
+	// go back until the hit is sent before the offset, then forward to the first hit after it
+	n := uint64(max(math.Floor(estimate-1), 0))
+	for step := uint64(1); n > 0; step *= 2 {
+		if _, at := scheduleAnchor(s, n); at <= offset {
+			break
+		}
+		n -= min(step, n)
+	}
+
+	fromHits, from := scheduleAnchor(s, n)
+	for {
+		toHits, to := scheduleAnchor(s, n+1)
+		if to == math.MaxInt64 {
+			return fromHits
+		}
+		if to > offset {
+			return fromHits + (toHits-fromHits)*float64(offset-from)/float64(to-from)
+		}
+		n++
+		fromHits, from = toHits, to
+	}
+}
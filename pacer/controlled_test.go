@@ -0,0 +1,209 @@
+package pacer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestControlledPacer(t *testing.T) {
+	tests := []struct {
+		name    string
+		control func(p *ControlledPacer)
+		// changed is the offset of the latest change, the expected hits are only known exactly from it on
+		changed time.Duration
+		hits    []float64
+		elapsed time.Duration
+		pace    map[uint64]time.Duration
+	}{
+		{
+			name:    "unchanged",
+			control: func(p *ControlledPacer) {},
+			hits:    []float64{0, 10, 20, 30, 40},
+			elapsed: 1500 * time.Millisecond,
+			pace:    map[uint64]time.Duration{0: 0, 15: 100 * time.Millisecond, 20: 600 * time.Millisecond},
+		},
+		{
+			name:    "scaled",
+			control: func(p *ControlledPacer) { p.Scale(time.Second, 2) },
+			changed: time.Second,
+			hits:    []float64{0, 10, 30, 50, 70},
+			elapsed: 1500 * time.Millisecond,
+			pace:    map[uint64]time.Duration{20: 50 * time.Millisecond, 25: 300 * time.Millisecond},
+		},
+		{
+			name:    "fixed rate",
+			control: func(p *ControlledPacer) { p.SetRate(time.Second, 5) },
+			changed: time.Second,
+			hits:    []float64{0, 10, 15, 20, 25},
+			elapsed: 2 * time.Second,
+			pace:    map[uint64]time.Duration{15: 200 * time.Millisecond, 10: 0},
+		},
+		{
+			name: "paused",
+			control: func(p *ControlledPacer) {
+				p.Pause(2 * time.Second)
+				p.Resume(3 * time.Second)
+			},
+			changed: 3 * time.Second,
+			hits:    []float64{0, 10, 20, 20, 30},
+			elapsed: 3 * time.Second,
+			// the hits after the pause follow the schedule of the wrapped pacer shifted by the pause
+			pace: map[uint64]time.Duration{19: 0, 20: 100 * time.Millisecond, 30: 1100 * time.Millisecond},
+		},
+		{
+			name: "reset",
+			control: func(p *ControlledPacer) {
+				p.Scale(time.Second, 0.5)
+				p.Reset(3 * time.Second)
+			},
+			changed: 3 * time.Second,
+			hits:    []float64{0, 10, 15, 20, 30},
+			elapsed: 3 * time.Second,
+			pace:    map[uint64]time.Duration{20: 100 * time.Millisecond},
+		},
+		{
+			name:    "stopped",
+			control: func(p *ControlledPacer) { p.Scale(time.Second, 0) },
+			changed: time.Second,
+			hits:    []float64{0, 10, 10, 10, 10},
+			elapsed: 2 * time.Second,
+			pace:    map[uint64]time.Duration{10: never},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewControlledPacer(NewConstantPacer(perSec(10)))
+			tt.control(p)
+
+			for i, hits := range tt.hits {
+				elapsed := time.Duration(i) * time.Second
+				if elapsed > 0 && elapsed < tt.changed {
+					continue
+				}
+				if got := ExpectedHits(p, elapsed); !nearHits(got, hits) {
+					t.Errorf("ExpectedHits(%v) = %v, want %v", elapsed, got, hits)
+				}
+			}
+			paceTests := make([]paceTest, 0, len(tt.pace))
+			for hits, want := range tt.pace {
+				paceTests = append(paceTests, paceTest{elapsed: tt.elapsed, hits: hits, want: want})
+			}
+			testPace(t, p, paceTests)
+		})
+	}
+}
+
+func TestControlledPacerManyChanges(t *testing.T) {
+	p := NewControlledPacer(NewConstantPacer(perSec(10)))
+	// the expected hits only depend on the current state and the hits at the latest change
+	for i := 1; i <= 10000; i++ {
+		p.Scale(time.Duration(i)*time.Millisecond, float64(1+i%2)/float64(2-i%2))
+	}
+
+	// 5000 milliseconds each at 10/s and 20/s after the first 1ms at 10/s
+	testHits(t, p, []hitsTest{
+		{elapsed: 10 * time.Second, hits: 150, rate: 10},
+		{elapsed: 11 * time.Second, hits: 160, rate: 10},
+	})
+}
+
+func TestControlledPacerState(t *testing.T) {
+	p := NewControlledPacer(NewConstantPacer(perSec(10)))
+	p.ScaleBase(0.5)
+
+	tests := []struct {
+		control func()
+		elapsed time.Duration
+		state   ControlState
+		rate    float64
+	}{
+		{control: func() {}, elapsed: 0, state: ControlState{Scale: 1}, rate: 5},
+		{control: func() { p.Scale(time.Second, 2) }, elapsed: time.Second, state: ControlState{Scale: 2}, rate: 10},
+		{control: func() { p.SetRate(2*time.Second, 3) }, elapsed: 2 * time.Second, state: ControlState{Scale: 2, FixedRate: 3}, rate: 3},
+		{control: func() { p.Pause(3 * time.Second) }, elapsed: 3 * time.Second, state: ControlState{Scale: 2, FixedRate: 3, Paused: true}, rate: 0},
+		// the base scale is kept
+		{control: func() { p.Reset(4 * time.Second) }, elapsed: 4 * time.Second, state: ControlState{Scale: 1}, rate: 5},
+	}
+	for _, tt := range tests {
+		tt.control()
+		if state := p.State(tt.elapsed); state != tt.state {
+			t.Errorf("State(%v) = %+v, want %+v", tt.elapsed, state, tt.state)
+		}
+		if got := p.Rate(tt.elapsed); got != tt.rate {
+			t.Errorf("Rate(%v) = %v, want %v", tt.elapsed, got, tt.rate)
+		}
+	}
+	// 5/s until 1s, 10/s until 2s, 3/s until 3s, paused until 4s, 5/s afterward
+	if got := ExpectedHits(p, 5*time.Second); !nearHits(got, 23) {
+		t.Errorf("ExpectedHits(5s) = %v, want 23", got)
+	}
+}
+
+func TestControlledPacerKeepsPattern(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trace := TraceFromTimestamps([]time.Time{start, start.Add(100 * time.Millisecond), start.Add(time.Second), start.Add(1100 * time.Millisecond)})
+
+	tests := map[string]func() Pacer{
+		"trace":   func() Pacer { return NewTracePacer(trace, 1, true) },
+		"poisson": func() Pacer { return NewPoissonPacer(perSec(10), 42) },
+	}
+	for name, pacer := range tests {
+		pacer := pacer
+		t.Run(name, func(t *testing.T) {
+			const hits = 40
+			offsets := make([]time.Duration, hits)
+			for i := range offsets {
+				offsets[i] = NextHitAt(pacer(), uint64(i), 0)
+			}
+			expectHitAt := func(p Pacer, hits uint64, want time.Duration) {
+				t.Helper()
+				if got := NextHitAt(p, hits, 0); !nearDuration(got, want) {
+					t.Errorf("NextHitAt(%d) = %v, want %v", hits, got, want)
+				}
+			}
+
+			// at twice the rate, another hit is sent halfway between two hits of the wrapped pacer
+			p := NewControlledPacer(pacer())
+			p.ScaleBase(2)
+			for i := 2; i < hits; i++ {
+				expectHitAt(p, uint64(2*i), (offsets[i-1]+offsets[i])/2)
+				expectHitAt(p, uint64(2*i+1), offsets[i])
+			}
+
+			// at half the rate, every other hit of the wrapped pacer is sent after the change
+			p = NewControlledPacer(pacer())
+			p.Scale(offsets[10], 0.5)
+			for i := 11; 2*i-10 < hits; i++ {
+				expectHitAt(p, uint64(i), offsets[2*i-10])
+			}
+		})
+	}
+}
+
+func TestControlledPacerFinished(t *testing.T) {
+	down := func() Pacer {
+		return NewStepPacer([]Step{{Rate: perSec(10), Hold: time.Second}, {Rate: perSec(0)}}, 0)
+	}
+
+	p := NewControlledPacer(down())
+	if Finished(p, 9) || !Finished(p, 10) {
+		t.Error("an unchanged controlled pacer should end with the wrapped pacer after 10 hits")
+	}
+
+	// twice the rate sends twice the hits of the wrapped pacer
+	p.Scale(0, 2)
+	if Finished(p, 19) || !Finished(p, 20) {
+		t.Error("a scaled controlled pacer should end with the wrapped pacer after 20 hits")
+	}
+
+	// a paused pacer may be resumed
+	p.Pause(2 * time.Second)
+	if Finished(p, 20) {
+		t.Error("a paused controlled pacer finished")
+	}
+
+	if Finished(NewControlledPacer(NewConstantPacer(perSec(10))), 100) {
+		t.Error("a controlled pacer with a rate > 0 finished")
+	}
+}
@@ -1,7 +1,7 @@
 package pacer
 
 import (
-	"github.com/rs/zerolog/log"
+	"errors"
 	"math"
 	"time"
 )
@@ -30,11 +30,8 @@ type linearRampUpPacer struct {
 
 // Pace determines the length of time to sleep until the next hit is sent.
 func (p linearRampUpPacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
-	switch {
-	case p.StartRate.Per == 0 || p.StartRate.Freq == 0:
+	if p.StartRate.Per == 0 || p.StartRate.Freq == 0 {
 		return 0 // Zero value = infinite rate
-	case p.StartRate.Per < 0 || p.StartRate.Freq < 0:
-		log.Fatal().Msg("rate can't be negative")
 	}
 
 	expectedHits := p.expectedHits(elapsed)
@@ -57,6 +54,19 @@ func (p linearRampUpPacer) Pace(elapsed time.Duration, hits uint64) time.Duratio
 	return wait
 }
 
+func (p linearRampUpPacer) validate() error {
+	if err := p.StartRate.validate(); err != nil {
+		return err
+	}
+	if err := p.TargetRate.validate(); err != nil {
+		return err
+	}
+	if p.RampUpDuration <= 0 {
+		return errors.New("ramp up duration must be > 0")
+	}
+	return nil
+}
+
 func (p linearRampUpPacer) Rate(elapsed time.Duration) float64 {
 	if elapsed > p.RampUpDuration {
 		return p.TargetRate.hitsPerSec()
@@ -1,6 +1,11 @@
 package pacer
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,6 +28,161 @@ type Rate struct {
 	Per  time.Duration // Time unit, usually 1s
 }
 
+// ParseRate parses a number of hits per time unit, e.g. 10/s, 600/m or 5/10s. A plain number is per second.
+func ParseRate(s string) (Rate, error) {
+	freq, per, found := strings.Cut(strings.TrimSpace(s), "/")
+	rate := Rate{Per: time.Second}
+
+	var err error
+	rate.Freq, err = strconv.Atoi(strings.TrimSpace(freq))
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid rate %q: frequency must be an integer", s)
+	}
+	if !found {
+		return rate, nil
+	}
+
+	per = strings.TrimSpace(per)
+	switch per {
+	case "ms", "s", "m", "h":
+		// a unit without a number is a single unit, e.g. 10/s
+		per = "1" + per
+	}
+	rate.Per, err = time.ParseDuration(per)
+	if err != nil || rate.Per <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: unit must be a positive duration", s)
+	}
+	return rate, nil
+}
+
 func (cp Rate) hitsPerSec() float64 {
 	return (float64(cp.Freq) / float64(cp.Per)) * 1e9
 }
+
+func (cp Rate) validate() error {
+	if cp.Freq < 0 || cp.Per < 0 {
+		return errors.New("rate can't be negative")
+	}
+	return nil
+}
+
+// validator is implemented by pacers which can detect an invalid configuration.
+type validator interface {
+	validate() error
+}
+
+// Validate returns an error if the Pacer is configured in a way it can't be used.
+func Validate(p Pacer) error {
+	if v, ok := p.(validator); ok {
+		return v.validate()
+	}
+	return nil
+}
+
+// hitsEstimator is implemented by pacers which can calculate the exact number of expected hits.
+type hitsEstimator interface {
+	expectedHits(elapsed time.Duration) float64
+}
+
+// ExpectedHits returns the number of hits a Pacer should have sent after the given elapsed duration.
+//
+// Pacers which don't provide an exact value are integrated numerically over their Rate.
+func ExpectedHits(p Pacer, elapsed time.Duration) float64 {
+	return ExpectedHitsSince(p, 0, 0, elapsed)
+}
+
+// ExpectedHitsSince returns the same as ExpectedHits, but continues from the given number of hits
+// expected at from, so pacers without an exact value are only integrated from there, e.g. for
+// periodic reports. If elapsed is before from, the pacer is integrated from the start.
+func ExpectedHitsSince(p Pacer, from time.Duration, hits float64, elapsed time.Duration) float64 {
+	if estimator, ok := p.(hitsEstimator); ok {
+		return estimator.expectedHits(elapsed)
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	if elapsed < from || from < 0 {
+		from, hits = 0, 0
+	}
+
+	const step = 100 * time.Millisecond
+	for t := from; t < elapsed; t += step {
+		dt := min(step, elapsed-t)
+		// trapezoidal rule
+		hits += (p.Rate(t) + p.Rate(t+dt)) / 2 * dt.Seconds()
+	}
+
+	return hits
+}
+
+// scheduler is implemented by pacers which don't follow their expected hits evenly.
+type scheduler interface {
+	nextHitAt(hits uint64) time.Duration
+}
+
+// NextHitAt returns the offset at which the pacer expects the next hit after the given number of hits
+// has been sent. The search starts at the given offset, which must not be after the result, e.g.
+// the offset of the previous hit.
+func NextHitAt(p Pacer, hits uint64, from time.Duration) time.Duration {
+	if hits == 0 {
+		return 0
+	}
+	if s, ok := p.(scheduler); ok {
+		return s.nextHitAt(hits)
+	}
+	// the first hit is sent immediately, every further hit when the expected hits reach its number
+	return offsetOf(p, float64(hits+1), from)
+}
+
+// finisher is implemented by pacers which stop sending hits at some point.
+type finisher interface {
+	finished(hits uint64) bool
+}
+
+// Finished returns true if the pacer doesn't expect any further hit after the given number of hits
+// has been sent, e.g. because it ramped down to zero. Pacers which may send hits again, e.g. after a
+// pause, are never finished.
+func Finished(p Pacer, hits uint64) bool {
+	if f, ok := p.(finisher); ok {
+		return f.finished(hits)
+	}
+	return false
+}
+
+// offsetOf searches the offset at which the pacer expects the given number of hits, starting at from.
+// If no further hits are expected for a day, math.MaxInt64 is returned.
+func offsetOf(p Pacer, hits float64, from time.Duration) time.Duration {
+	missing := hits - ExpectedHits(p, from)
+	if missing <= 0 {
+		return from
+	}
+
+	// guess the first step based on the current rate
+	step := time.Second
+	if rate := p.Rate(from); rate > 0 {
+		step = max(time.Duration(missing/rate*1e9), time.Microsecond)
+	}
+
+	lower, upper := from, from+step
+	for expected := ExpectedHits(p, upper); expected < hits; {
+		if upper-from > math.MaxInt64/4 {
+			return math.MaxInt64
+		}
+		lower, upper = upper, from+2*(upper-from)
+		next := ExpectedHits(p, upper)
+		if upper-lower > 24*time.Hour && next <= expected {
+			return math.MaxInt64
+		}
+		expected = next
+	}
+	for upper-lower > time.Microsecond {
+		middle := lower + (upper-lower)/2
+		if ExpectedHits(p, middle) < hits {
+			lower = middle
+		} else {
+			upper = middle
+		}
+	}
+
+	return upper
+}
@@ -0,0 +1,167 @@
+package pacer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// never is the wait of a pacer which doesn't expect further hits, see paceTest.
+const never = time.Duration(math.MaxInt64)
+
+func perSec(freq int) Rate {
+	return Rate{Freq: freq, Per: time.Second}
+}
+
+// hitsTest is the number of expected hits and the rate of a pacer at the elapsed duration.
+type hitsTest struct {
+	elapsed time.Duration
+	hits    float64
+	rate    float64
+}
+
+func testHits(t *testing.T, p Pacer, tests []hitsTest) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := ExpectedHits(p, tt.elapsed); !nearHits(got, tt.hits) {
+			t.Errorf("ExpectedHits(%v) = %v, want %v", tt.elapsed, got, tt.hits)
+		}
+		if got := p.Rate(tt.elapsed); math.Abs(got-tt.rate) > 1e-9 {
+			t.Errorf("Rate(%v) = %v, want %v", tt.elapsed, got, tt.rate)
+		}
+	}
+}
+
+// paceTest is the wait of a pacer after the given number of hits at the elapsed duration. A wait of
+// never expects no further hits.
+type paceTest struct {
+	elapsed time.Duration
+	hits    uint64
+	want    time.Duration
+}
+
+func testPace(t *testing.T, p Pacer, tests []paceTest) {
+	t.Helper()
+	for _, tt := range tests {
+		got := p.Pace(tt.elapsed, tt.hits)
+		if tt.want == never {
+			if got != never-tt.elapsed {
+				t.Errorf("Pace(%v, %d) = %v, expected no further hits", tt.elapsed, tt.hits, got)
+			}
+			continue
+		}
+		if !nearDuration(got, tt.want) {
+			t.Errorf("Pace(%v, %d) = %v, want %v", tt.elapsed, tt.hits, got, tt.want)
+		}
+	}
+}
+
+// testInvalid expects each pacer to fail the validation.
+func testInvalid(t *testing.T, pacers map[string]Pacer) {
+	t.Helper()
+	for name, p := range pacers {
+		if err := Validate(p); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func nearHits(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-6
+}
+
+// nearDuration allows for the rounding of durations to nanoseconds and the precision of searches.
+func nearDuration(got, want time.Duration) bool {
+	diff := got - want
+	return diff >= -time.Millisecond && diff <= time.Millisecond
+}
+
+func TestExpectedHitsSince(t *testing.T) {
+	// a pacer without an exact number of expected hits is integrated numerically
+	p := rateFunc(func(elapsed time.Duration) float64 { return elapsed.Seconds() })
+
+	hits := 0.0
+	at := time.Duration(0)
+	for _, elapsed := range []time.Duration{time.Second, 2500 * time.Millisecond, 4 * time.Second} {
+		hits = ExpectedHitsSince(p, at, hits, elapsed)
+		at = elapsed
+		want := elapsed.Seconds() * elapsed.Seconds() / 2
+		if !nearHits(hits, want) {
+			t.Errorf("ExpectedHitsSince(%v) = %v, want %v", elapsed, hits, want)
+		}
+		testHits(t, p, []hitsTest{{elapsed: elapsed, hits: want, rate: elapsed.Seconds()}})
+	}
+
+	// an earlier elapsed duration starts from the beginning
+	if got := ExpectedHitsSince(p, at, hits, time.Second); !nearHits(got, 0.5) {
+		t.Errorf("ExpectedHitsSince(1s) = %v, want 0.5", got)
+	}
+}
+
+// rateFunc is a pacer which only defines its rate.
+type rateFunc func(elapsed time.Duration) float64
+
+func (f rateFunc) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	return 0
+}
+
+func (f rateFunc) Rate(elapsed time.Duration) float64 {
+	return f(elapsed)
+}
+
+func TestNextHitAt(t *testing.T) {
+	p := NewConstantPacer(perSec(10))
+
+	tests := []struct {
+		hits uint64
+		from time.Duration
+		want time.Duration
+	}{
+		// the first hit is sent immediately, every further one once one more hit is expected
+		{hits: 0, want: 0},
+		{hits: 1, want: 200 * time.Millisecond},
+		{hits: 9, want: time.Second},
+		{hits: 9, from: 500 * time.Millisecond, want: time.Second},
+		{hits: 99, from: 9 * time.Second, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := NextHitAt(p, tt.hits, tt.from); !nearDuration(got, tt.want) {
+			t.Errorf("NextHitAt(%d, %v) = %v, want %v", tt.hits, tt.from, got, tt.want)
+		}
+	}
+
+	// no further hit is expected after a ramp down to zero
+	if got := NextHitAt(NewRampPacer(perSec(10), perSec(0), 2*time.Second), 10, 0); got != never {
+		t.Errorf("NextHitAt(10) = %v, expected no further hits", got)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{in: "10/s", want: perSec(10)},
+		{in: " 600 / m ", want: Rate{Freq: 600, Per: time.Minute}},
+		{in: "5/10s", want: Rate{Freq: 5, Per: 10 * time.Second}},
+		{in: "3/ms", want: Rate{Freq: 3, Per: time.Millisecond}},
+		{in: "7", want: perSec(7)},
+		{in: "0/s", want: perSec(0)},
+		{in: "1.5/s", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/-1s", wantErr: true},
+		{in: "10/x", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package pacer
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// NewPoissonPacer creates a pacer which sends hits with exponentially distributed intervals
+// averaging the given rate, like independent users arriving at a service. Runs with the same
+// seed produce the same intervals, a seed of 0 picks a random seed.
+func NewPoissonPacer(rate Rate, seed int64) Pacer {
+	return NewPoissonArrivalPacer(NewConstantPacer(rate), seed)
+}
+
+// NewPoissonArrivalPacer randomizes the hits of the given pacer: the intervals between hits are
+// exponentially distributed while the rate follows the Rate of the wrapped pacer on average.
+func NewPoissonArrivalPacer(p Pacer, seed int64) Pacer {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &poissonPacer{
+		pacer: p,
+		seed:  seed,
+		rand:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+type poissonPacer struct {
+	pacer Pacer
+	seed  int64
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	// nextArrival is the number of expected hits of the wrapped pacer at which the hit after the
+	// given number of hits is sent
+	nextArrival float64
+	hits        uint64
+	// at is the offset of the latest arrival, which is where the search for the next one starts
+	at time.Duration
+	// the previous arrival is kept for pacers which interpolate between two hits, see ControlledPacer
+	prevArrival float64
+	prevAt      time.Duration
+}
+
+func (p *poissonPacer) validate() error {
+	if p.pacer == nil {
+		return errors.New("poisson pacer needs a pacer to wrap")
+	}
+	return Validate(p.pacer)
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p *poissonPacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	if hits == 0 {
+		// a new execution starts, which gets the same intervals as the previous one
+		p.reset()
+		return 0
+	}
+
+	// The arrivals of a poisson process are evenly spaced in the number of expected hits of
+	// the wrapped pacer if the gaps are drawn from an exponential distribution with a mean of 1.
+	arrival, _ := p.arrival(hits)
+	next := offsetOf(p.pacer, arrival, elapsed)
+	return min(max(next-elapsed, 0), math.MaxInt64-elapsed)
+}
+
+func (p *poissonPacer) nextHitAt(hits uint64) time.Duration {
+	arrival, from := p.arrival(hits)
+	next := offsetOf(p.pacer, arrival, from)
+	if next != math.MaxInt64 {
+		p.mu.Lock()
+		if p.hits == hits {
+			p.at = max(p.at, next)
+		}
+		p.mu.Unlock()
+	}
+	return next
+}
+
+// arrival returns the number of expected hits at which the hit after the given number of hits is due
+// and an offset which isn't after it.
+func (p *poissonPacer) arrival(hits uint64) (float64, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// hits only increase during an execution, so only the latest two arrivals have to be kept.
+	// Earlier arrivals are drawn again from the start.
+	if hits+1 == p.hits {
+		return p.prevArrival, p.prevAt
+	}
+	if hits < p.hits {
+		p.resetLocked()
+	}
+	for p.hits < hits {
+		p.prevArrival, p.prevAt = p.nextArrival, p.at
+		p.nextArrival += p.rand.ExpFloat64()
+		p.hits++
+	}
+
+	return p.nextArrival, p.at
+}
+
+// reset starts the sequence of intervals from the beginning.
+func (p *poissonPacer) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resetLocked()
+}
+
+func (p *poissonPacer) resetLocked() {
+	p.rand = rand.New(rand.NewSource(p.seed))
+	p.nextArrival = 0
+	p.hits = 0
+	p.at = 0
+	p.prevArrival = 0
+	p.prevAt = 0
+}
+
+func (p *poissonPacer) Rate(elapsed time.Duration) float64 {
+	return p.pacer.Rate(elapsed)
+}
+
+func (p *poissonPacer) expectedHits(t time.Duration) float64 {
+	return ExpectedHits(p.pacer, t)
+}
@@ -0,0 +1,81 @@
+package pacer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPoissonPacer(t *testing.T) {
+	tests := map[string]Pacer{
+		"constant": NewPoissonPacer(perSec(100), 42),
+		"ramp":     NewPoissonArrivalPacer(NewRampPacer(perSec(100), perSec(200), 10*time.Second), 42),
+	}
+	for name, p := range tests {
+		p := p
+		t.Run(name, func(t *testing.T) {
+			if err := Validate(p); err != nil {
+				t.Fatal(err)
+			}
+
+			const hits = 2000
+			offsets := poissonOffsets(p, hits)
+			for i := 1; i < len(offsets); i++ {
+				if offsets[i] < offsets[i-1] {
+					t.Fatalf("hit %d at %v is before hit %d at %v", i+1, offsets[i], i, offsets[i-1])
+				}
+			}
+
+			// on average, the hits follow the wrapped pacer
+			last := offsets[len(offsets)-1]
+			if expected := ExpectedHits(p, last); math.Abs(expected-hits)/hits > 0.1 {
+				t.Errorf("%d hits were sent within %v, expected %.0f", hits, last, expected)
+			}
+
+			// a new execution gets the same intervals
+			if again := poissonOffsets(p, hits); again[len(again)-1] != last {
+				t.Errorf("the last hit of the second execution is at %v, expected %v", again[len(again)-1], last)
+			}
+		})
+	}
+}
+
+func TestPoissonPacerSeed(t *testing.T) {
+	a := poissonOffsets(NewPoissonPacer(perSec(10), 1), 100)
+	b := poissonOffsets(NewPoissonPacer(perSec(10), 1), 100)
+	c := poissonOffsets(NewPoissonPacer(perSec(10), 2), 100)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("hit %d is at %v and %v with the same seed", i+1, a[i], b[i])
+		}
+	}
+	if a[len(a)-1] == c[len(c)-1] {
+		t.Error("different seeds produced the same intervals")
+	}
+}
+
+func TestPoissonPacerNextHitAt(t *testing.T) {
+	offsets := poissonOffsets(NewPoissonPacer(perSec(10), 7), 50)
+
+	p := NewPoissonPacer(perSec(10), 7)
+	for i, want := range offsets {
+		if got := NextHitAt(p, uint64(i), 0); !nearDuration(got, want) {
+			t.Errorf("NextHitAt(%d) = %v, want %v", i, got, want)
+		}
+	}
+	// going back to an earlier hit recreates the intervals
+	if got := NextHitAt(p, 10, 0); !nearDuration(got, offsets[10]) {
+		t.Errorf("NextHitAt(10) = %v, want %v", got, offsets[10])
+	}
+}
+
+// poissonOffsets simulates an execution which sends each hit as soon as it's due and returns their offsets.
+func poissonOffsets(p Pacer, hits int) []time.Duration {
+	offsets := make([]time.Duration, 0, hits)
+	elapsed := time.Duration(0)
+	for i := 0; i < hits; i++ {
+		elapsed += p.Pace(elapsed, uint64(i))
+		offsets = append(offsets, elapsed)
+	}
+	return offsets
+}
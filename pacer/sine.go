@@ -0,0 +1,124 @@
+package pacer
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// NewSinePacer creates a pacer whose rate follows a sine wave around the mean rate, e.g. to simulate
+// a compressed day-night cycle of traffic. The rate oscillates between mean-amplitude and
+// mean+amplitude once per period. The phase shifts the curve, the execution starts at this offset
+// into the period: a phase of 0 starts at the mean rate going up, period/4 at the peak and
+// 3*period/4 at the trough.
+func NewSinePacer(mean Rate, amplitude Rate, period time.Duration, phase time.Duration) Pacer {
+	return sinePacer{
+		mean:      mean,
+		amplitude: amplitude,
+		period:    period,
+		phase:     phase,
+	}
+}
+
+type sinePacer struct {
+	mean      Rate
+	amplitude Rate
+	period    time.Duration
+	phase     time.Duration
+}
+
+func (p sinePacer) validate() error {
+	if err := p.mean.validate(); err != nil {
+		return err
+	}
+	if err := p.amplitude.validate(); err != nil {
+		return err
+	}
+	if p.amplitude.hitsPerSec() > p.mean.hitsPerSec() {
+		return errors.New("amplitude can't be larger than the mean rate")
+	}
+	if p.period <= 0 {
+		return errors.New("period must be > 0")
+	}
+	return nil
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p sinePacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	expectedHits := p.expectedHits(elapsed)
+	if hits == 0 || hits < uint64(expectedHits) {
+		// Running behind, send next hit immediately.
+		return 0
+	}
+
+	mean := p.mean.hitsPerSec()
+	if mean <= 0 {
+		return math.MaxInt64 - elapsed
+	}
+
+	// expectedHits is monotonic, so the offset of the next hit is bracketed between the time
+	// needed at the maximum and at the minimum rate.
+	target := float64(hits + 1)
+	missing := target - expectedHits
+	lower := elapsed.Seconds()
+	upper := math.Inf(1)
+	if minimum := mean - p.amplitude.hitsPerSec(); minimum > 0 {
+		upper = lower + missing/minimum
+	}
+
+	// Newton's method falling back to bisection if a step leaves the bracket
+	x := lower + missing/mean
+	for i := 0; i < 64; i++ {
+		diff := p.hitsAt(x) - target
+		if diff < 0 {
+			lower = x
+		} else {
+			upper = x
+		}
+		if math.Abs(diff) < 1e-9 {
+			break
+		}
+
+		next := x - diff/p.rateAt(x)
+		if math.IsNaN(next) || next <= lower || next >= upper {
+			if math.IsInf(upper, 1) {
+				next = lower + 2*(x-lower) + p.period.Seconds()
+			} else {
+				next = lower + (upper-lower)/2
+			}
+		}
+		x = next
+	}
+
+	if x*1e9 >= math.MaxInt64 {
+		return math.MaxInt64 - elapsed
+	}
+	return max(time.Duration(x*1e9)-elapsed, 0)
+}
+
+func (p sinePacer) Rate(elapsed time.Duration) float64 {
+	return p.rateAt(elapsed.Seconds())
+}
+
+func (p sinePacer) expectedHits(t time.Duration) float64 {
+	if t < 0 {
+		return 0
+	}
+
+	return p.hitsAt(t.Seconds())
+}
+
+// rateAt returns the rate x seconds after the start.
+func (p sinePacer) rateAt(x float64) float64 {
+	return p.mean.hitsPerSec() + p.amplitude.hitsPerSec()*math.Sin(p.angle(x))
+}
+
+// hitsAt returns the integral of the rate from the start until x seconds.
+func (p sinePacer) hitsAt(x float64) float64 {
+	omega := 2 * math.Pi / p.period.Seconds()
+	return p.mean.hitsPerSec()*x + p.amplitude.hitsPerSec()/omega*(math.Cos(p.angle(0))-math.Cos(p.angle(x)))
+}
+
+func (p sinePacer) angle(x float64) float64 {
+	return 2 * math.Pi * (x + p.phase.Seconds()) / p.period.Seconds()
+}
@@ -0,0 +1,69 @@
+package pacer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSinePacer(t *testing.T) {
+	p := NewSinePacer(perSec(10), perSec(5), 4*time.Second, 0)
+	if err := Validate(p); err != nil {
+		t.Fatal(err)
+	}
+
+	testHits(t, p, []hitsTest{
+		{elapsed: 0, hits: 0, rate: 10},
+		{elapsed: time.Second, hits: 10 + 10/math.Pi, rate: 15},
+		{elapsed: 2 * time.Second, hits: 20 + 20/math.Pi, rate: 10},
+		{elapsed: 3 * time.Second, hits: 30 + 10/math.Pi, rate: 5},
+		{elapsed: 4 * time.Second, hits: 40, rate: 10},
+		{elapsed: 40 * time.Second, hits: 400, rate: 10},
+	})
+
+	// the next hit is due when one more hit is expected
+	testNextHits(t, p, 100)
+	for hits := uint64(1); hits < 100; hits++ {
+		next := NextHitAt(p, hits, 0)
+		elapsed := next - 30*time.Millisecond
+		testPace(t, p, []paceTest{{elapsed: elapsed, hits: hits, want: next - elapsed}})
+	}
+}
+
+func TestSinePacerPhase(t *testing.T) {
+	for phase, rate := range map[time.Duration]float64{0: 10, time.Second: 15, 3 * time.Second: 5} {
+		p := NewSinePacer(perSec(10), perSec(5), 4*time.Second, phase)
+		// a full period always has the mean rate
+		testHits(t, p, []hitsTest{{elapsed: 0, hits: 0, rate: rate}})
+		if got := ExpectedHits(p, 4*time.Second); !nearHits(got, 40) {
+			t.Errorf("ExpectedHits(4s) with a phase of %v = %v, want 40", phase, got)
+		}
+	}
+}
+
+func TestSinePacerDownToZero(t *testing.T) {
+	// the rate touches zero at the trough without stalling
+	p := NewSinePacer(perSec(10), perSec(10), 4*time.Second, 0)
+
+	testHits(t, p, []hitsTest{{elapsed: 3 * time.Second, hits: 30 + 20/math.Pi, rate: 0}})
+	testNextHits(t, p, 60)
+}
+
+func TestSinePacerValidate(t *testing.T) {
+	testInvalid(t, map[string]Pacer{
+		"amplitude above mean": NewSinePacer(perSec(5), perSec(10), time.Second, 0),
+		"no period":            NewSinePacer(perSec(10), perSec(5), 0, 0),
+		"negative mean":        NewSinePacer(perSec(-10), perSec(5), time.Second, 0),
+	})
+}
+
+// testNextHits expects each of the given number of hits to be due when one more hit is expected.
+func testNextHits(t *testing.T, p Pacer, hits uint64) {
+	t.Helper()
+	for n := uint64(1); n < hits; n++ {
+		next := NextHitAt(p, n, 0)
+		if got := ExpectedHits(p, next); math.Abs(got-float64(n+1)) > 1e-3 {
+			t.Errorf("ExpectedHits(NextHitAt(%d)) = %v, want %d", n, got, n+1)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package pacer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePacer creates a pacer from a short specification in the form type:arguments, e.g. for flags:
+//
+//	10/s                           constant rate, see ParseRate
+//	constant:10/s
+//	ramp:1/s,50/s,2m               start rate, target rate and ramp duration
+//	step:10/s@1m,20/s@1m,40/s      rates held for the given durations, the last one afterward
+//	sine:20/s,10/s,10m[,phase]     mean rate, amplitude, period and an optional phase
+//	poisson:10/s[,seed]            rate and an optional seed
+func ParsePacer(spec string) (Pacer, error) {
+	kind, args, found := strings.Cut(strings.TrimSpace(spec), ":")
+	if !found {
+		rate, err := ParseRate(kind)
+		if err != nil {
+			return nil, err
+		}
+		return NewConstantPacer(rate), nil
+	}
+
+	parts := strings.Split(args, ",")
+	switch kind {
+	case "constant":
+		if len(parts) != 1 {
+			return nil, errors.New("constant pacer expects a rate, e.g. constant:10/s")
+		}
+		rate, err := ParseRate(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		return NewConstantPacer(rate), nil
+	case "ramp":
+		if len(parts) != 3 {
+			return nil, errors.New("ramp pacer expects start rate, target rate and duration, e.g. ramp:1/s,50/s,2m")
+		}
+		startRate, err := ParseRate(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		targetRate, err := ParseRate(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		rampDuration, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+		if err != nil {
+			return nil, err
+		}
+		return NewRampPacer(startRate, targetRate, rampDuration), nil
+	case "step":
+		steps := make([]Step, 0, len(parts))
+		for _, part := range parts {
+			rate, hold, _ := strings.Cut(part, "@")
+			step := Step{}
+			var err error
+			if step.Rate, err = ParseRate(rate); err != nil {
+				return nil, err
+			}
+			if hold != "" {
+				if step.Hold, err = time.ParseDuration(strings.TrimSpace(hold)); err != nil {
+					return nil, err
+				}
+			}
+			steps = append(steps, step)
+		}
+		return NewStepPacer(steps, 0), nil
+	case "sine":
+		if len(parts) != 3 && len(parts) != 4 {
+			return nil, errors.New("sine pacer expects mean, amplitude, period and an optional phase, e.g. sine:20/s,10/s,10m")
+		}
+		mean, err := ParseRate(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		amplitude, err := ParseRate(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		period, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+		if err != nil {
+			return nil, err
+		}
+		phase := time.Duration(0)
+		if len(parts) == 4 {
+			if phase, err = time.ParseDuration(strings.TrimSpace(parts[3])); err != nil {
+				return nil, err
+			}
+		}
+		return NewSinePacer(mean, amplitude, period, phase), nil
+	case "poisson":
+		if len(parts) != 1 && len(parts) != 2 {
+			return nil, errors.New("poisson pacer expects a rate and an optional seed, e.g. poisson:10/s,42")
+		}
+		rate, err := ParseRate(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		seed := int64(0)
+		if len(parts) == 2 {
+			if seed, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid seed %q", parts[1])
+			}
+		}
+		return NewPoissonPacer(rate, seed), nil
+	default:
+		return nil, fmt.Errorf("unknown pacer %q, expected constant, ramp, step, sine or poisson", kind)
+	}
+}
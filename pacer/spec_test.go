@@ -0,0 +1,83 @@
+package pacer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePacer(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Pacer
+	}{
+		{spec: "10/s", want: NewConstantPacer(perSec(10))},
+		{spec: "constant:600/m", want: NewConstantPacer(perSec(10))},
+		{spec: "ramp:1/s,50/s,2m", want: NewRampPacer(perSec(1), perSec(50), 2*time.Minute)},
+		{spec: "ramp:50/s,0/s,1m", want: NewRampPacer(perSec(50), perSec(0), time.Minute)},
+		{spec: "step:10/s@1m,20/s@1m,40/s", want: NewStepPacer([]Step{
+			{Rate: perSec(10), Hold: time.Minute},
+			{Rate: perSec(20), Hold: time.Minute},
+			{Rate: perSec(40)},
+		}, 0)},
+		{spec: "sine:20/s,10/s,10m", want: NewSinePacer(perSec(20), perSec(10), 10*time.Minute, 0)},
+		{spec: "sine:20/s,10/s,10m,150s", want: NewSinePacer(perSec(20), perSec(10), 10*time.Minute, 150*time.Second)},
+		{spec: "poisson:10/s,42", want: NewPoissonPacer(perSec(10), 42)},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePacer(tt.spec)
+			if err != nil {
+				t.Fatalf("ParsePacer(%q) error = %v", tt.spec, err)
+			}
+			if err := Validate(got); err != nil {
+				t.Fatalf("ParsePacer(%q) is invalid: %v", tt.spec, err)
+			}
+
+			var hitsTests []hitsTest
+			for _, elapsed := range []time.Duration{0, time.Second, 90 * time.Second, 3 * time.Minute, 11 * time.Minute} {
+				hitsTests = append(hitsTests, hitsTest{elapsed: elapsed, hits: ExpectedHits(tt.want, elapsed), rate: tt.want.Rate(elapsed)})
+			}
+			testHits(t, got, hitsTests)
+			for hits := uint64(0); hits < 5; hits++ {
+				if got, want := NextHitAt(got, hits, 0), NextHitAt(tt.want, hits, 0); !nearDuration(got, want) {
+					t.Errorf("NextHitAt(%d) = %v, want %v", hits, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePacerRandomSeed(t *testing.T) {
+	// without a seed, the intervals are random, so only the rate can be compared
+	p, err := ParsePacer("poisson:10/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHits(t, p, []hitsTest{{elapsed: time.Minute, hits: 600, rate: 10}})
+}
+
+func TestParsePacerErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                       "invalid rate",
+		"fast":                   "invalid rate",
+		"constant:":              "invalid rate",
+		"constant:10/s,20/s":     "constant pacer expects a rate",
+		"ramp:1/s,50/s":          "ramp pacer expects start rate, target rate and duration",
+		"ramp:1/s,50/s,soon":     "invalid duration",
+		"step:10/s@never":        "invalid duration",
+		"step:10/s@1m,fast":      "invalid rate",
+		"sine:20/s,10/s":         "sine pacer expects mean, amplitude, period and an optional phase",
+		"sine:20/s,10/s,1m,late": "invalid duration",
+		"poisson:10/s,1,2":       "poisson pacer expects a rate and an optional seed",
+		"poisson:10/s,seed":      `invalid seed "seed"`,
+		"burst:10/s":             `unknown pacer "burst"`,
+	}
+	for spec, want := range tests {
+		_, err := ParsePacer(spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParsePacer(%q) error = %v, want %q", spec, err, want)
+		}
+	}
+}
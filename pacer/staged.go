@@ -0,0 +1,127 @@
+package pacer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Stage runs a pacer for the given duration as part of a staged pacer.
+type Stage struct {
+	Pacer    Pacer
+	Duration time.Duration
+}
+
+// NewStagedPacer creates a pacer which runs the given pacers one after another. Each pacer
+// sees the elapsed time and hits relative to the start of its stage. After the last stage
+// has ended, its pacer keeps being used, so its duration may be zero.
+func NewStagedPacer(stages ...Stage) Pacer {
+	p := stagedPacer{
+		stages: stages,
+		starts: make([]time.Duration, len(stages)),
+		hits:   make([]float64, len(stages)),
+	}
+
+	start := time.Duration(0)
+	hits := 0.0
+	for i, stage := range stages {
+		p.starts[i] = start
+		p.hits[i] = hits
+		if stage.Pacer != nil && stage.Duration > 0 {
+			start += stage.Duration
+			hits += ExpectedHits(stage.Pacer, stage.Duration)
+		}
+	}
+
+	return p
+}
+
+type stagedPacer struct {
+	stages []Stage
+	// starts contains the offset at which each stage starts
+	starts []time.Duration
+	// hits contains the number of expected hits at the start of each stage
+	hits []float64
+}
+
+func (p stagedPacer) validate() error {
+	if len(p.stages) == 0 {
+		return errors.New("staged pacer needs at least one stage")
+	}
+	for i, stage := range p.stages {
+		if stage.Pacer == nil {
+			return fmt.Errorf("stage %d has no pacer", i+1)
+		}
+		if stage.Duration <= 0 && i < len(p.stages)-1 {
+			return fmt.Errorf("stage %d must have a duration > 0", i+1)
+		}
+		if err := Validate(stage.Pacer); err != nil {
+			return fmt.Errorf("stage %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p stagedPacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	i := p.stage(elapsed)
+	stage := p.stages[i]
+	offset := elapsed - p.starts[i]
+
+	hitsBefore := uint64(math.Floor(p.hits[i]))
+	if hits < hitsBefore {
+		// Running behind, send next hit immediately.
+		return 0
+	}
+
+	stageHits := hits - hitsBefore
+	if stageHits > 0 || i == 0 {
+		wait := stage.Pacer.Pace(offset, stageHits)
+		if i == len(p.stages)-1 || offset+wait <= stage.Duration {
+			// the wait of a pacer without further hits may overflow the total elapsed time
+			return min(wait, math.MaxInt64-elapsed)
+		}
+	}
+
+	// Pacers send their first hit immediately, which would add an additional hit with every
+	// stage, and don't know about the following stages. Instead, search the time at which
+	// the next hit is due.
+	next := offsetOf(p, float64(hits+1), elapsed)
+	return min(max(next-elapsed, 0), math.MaxInt64-elapsed)
+}
+
+func (p stagedPacer) finished(hits uint64) bool {
+	// only the last stage is held forever
+	i := len(p.stages) - 1
+	if i < 0 || p.stages[i].Pacer == nil {
+		return false
+	}
+
+	hitsBefore := uint64(math.Floor(p.hits[i]))
+	return hits >= hitsBefore && Finished(p.stages[i].Pacer, hits-hitsBefore)
+}
+
+func (p stagedPacer) Rate(elapsed time.Duration) float64 {
+	i := p.stage(elapsed)
+	return p.stages[i].Pacer.Rate(elapsed - p.starts[i])
+}
+
+func (p stagedPacer) expectedHits(t time.Duration) float64 {
+	if t < 0 {
+		return 0
+	}
+
+	i := p.stage(t)
+	return p.hits[i] + ExpectedHits(p.stages[i].Pacer, t-p.starts[i])
+}
+
+// stage returns the index of the stage the elapsed duration falls into.
+func (p stagedPacer) stage(elapsed time.Duration) int {
+	for i := len(p.stages) - 1; i > 0; i-- {
+		if elapsed >= p.starts[i] {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package pacer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStagedPacer(t *testing.T) {
+	p := NewStagedPacer(
+		Stage{Pacer: NewConstantPacer(perSec(10)), Duration: time.Second},
+		Stage{Pacer: NewConstantPacer(perSec(20))},
+	)
+	if err := Validate(p); err != nil {
+		t.Fatal(err)
+	}
+
+	testHits(t, p, []hitsTest{
+		{elapsed: 500 * time.Millisecond, hits: 5, rate: 10},
+		{elapsed: time.Second, hits: 10, rate: 20},
+		{elapsed: 2 * time.Second, hits: 30, rate: 20},
+	})
+	testPace(t, p, []paceTest{
+		{elapsed: 0, hits: 0, want: 0},
+		{elapsed: 0, hits: 1, want: 200 * time.Millisecond},
+		{elapsed: 900 * time.Millisecond, hits: 9, want: 100 * time.Millisecond},
+		// the next hit of the first stage would be after its end
+		{elapsed: 950 * time.Millisecond, hits: 10, want: 100 * time.Millisecond},
+		// the second stage doesn't send an additional hit at its start
+		{elapsed: time.Second, hits: 10, want: 50 * time.Millisecond},
+		{elapsed: time.Second, hits: 9, want: 0},
+		{elapsed: 2 * time.Second, hits: 30, want: 50 * time.Millisecond},
+	})
+	if Finished(p, 100) {
+		t.Error("a staged pacer with a last rate > 0 finished")
+	}
+}
+
+func TestStagedPacerEndingAtZero(t *testing.T) {
+	p := NewStagedPacer(
+		Stage{Pacer: NewConstantPacer(perSec(10)), Duration: time.Second},
+		Stage{Pacer: NewRampPacer(perSec(10), perSec(0), 2*time.Second)},
+	)
+
+	testHits(t, p, []hitsTest{
+		{elapsed: 2 * time.Second, hits: 17.5, rate: 5},
+		{elapsed: time.Hour, hits: 20, rate: 0},
+	})
+	testPace(t, p, []paceTest{{elapsed: 4 * time.Second, hits: 20, want: never}})
+	for hits, want := range map[uint64]bool{10: false, 19: false, 20: true} {
+		if got := Finished(p, hits); got != want {
+			t.Errorf("Finished(%d) = %v, want %v", hits, got, want)
+		}
+	}
+}
+
+func TestRampPacerDownToZero(t *testing.T) {
+	p := NewRampPacer(perSec(10), perSec(0), 2*time.Second)
+	if err := Validate(p); err != nil {
+		t.Fatal(err)
+	}
+
+	testHits(t, p, []hitsTest{
+		{elapsed: 0, hits: 0, rate: 10},
+		{elapsed: time.Second, hits: 7.5, rate: 5},
+		{elapsed: 2 * time.Second, hits: 10, rate: 0},
+		{elapsed: time.Hour, hits: 10, rate: 0},
+	})
+	testPace(t, p, []paceTest{
+		// 10x - 2.5x² = 8 at x = 2 - 2/sqrt(5)
+		{elapsed: time.Second, hits: 7, want: 105572809 * time.Nanosecond},
+		// the last expected hit is due when the rate reaches zero
+		{elapsed: 1500 * time.Millisecond, hits: 9, want: 500 * time.Millisecond},
+		// no further hits are expected
+		{elapsed: 3 * time.Second, hits: 10, want: never},
+	})
+	if !Finished(p, 10) {
+		t.Error("the ramp down to zero didn't finish after the last hit")
+	}
+}
+
+func TestStagedPacerValidate(t *testing.T) {
+	constant := NewConstantPacer(perSec(10))
+
+	testInvalid(t, map[string]Pacer{
+		"no stages":     NewStagedPacer(),
+		"no pacer":      NewStagedPacer(Stage{Duration: time.Second}),
+		"no duration":   NewStagedPacer(Stage{Pacer: constant}, Stage{Pacer: constant}),
+		"invalid pacer": NewStagedPacer(Stage{Pacer: NewConstantPacer(perSec(-1))}),
+	})
+}
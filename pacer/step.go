@@ -0,0 +1,179 @@
+package pacer
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Step is a single stage of a step pacer which holds the rate for the given duration.
+type Step struct {
+	Rate Rate
+	Hold time.Duration
+}
+
+// NewStepPacer creates a pacer which goes through the given steps in order and holds the
+// rate of the last step afterward. If rampDuration is > 0, the rate is increased or decreased
+// linearly between two steps within the ramp duration, which is added to the hold durations.
+func NewStepPacer(steps []Step, rampDuration time.Duration) Pacer {
+	p := stepPacer{
+		Steps:        steps,
+		RampDuration: rampDuration,
+	}
+
+	start := time.Duration(0)
+	hits := 0.0
+	addSegment := func(duration time.Duration, startRate float64, endRate float64) {
+		if duration <= 0 {
+			return
+		}
+		s := segment{
+			start:     start,
+			duration:  duration,
+			startRate: startRate,
+			slope:     (endRate - startRate) / duration.Seconds(),
+			hits:      hits,
+		}
+		p.segments = append(p.segments, s)
+		start += duration
+		hits = s.expectedHits(duration)
+	}
+
+	for i, step := range steps {
+		rate := step.Rate.hitsPerSec()
+		if i == len(steps)-1 {
+			// the last step is held forever
+			addSegment(math.MaxInt64-start, rate, rate)
+			break
+		}
+		addSegment(step.Hold, rate, rate)
+		addSegment(rampDuration, rate, steps[i+1].Rate.hitsPerSec())
+	}
+
+	return p
+}
+
+// NewRampPacer creates a linear increase or decrease from the start to the target rate within
+// the given duration and holds the target rate afterward. Unlike NewLinearRampUpPacer it
+// supports ramping down and rates of zero.
+func NewRampPacer(startRate Rate, targetRate Rate, rampDuration time.Duration) Pacer {
+	return NewStepPacer([]Step{{Rate: startRate}, {Rate: targetRate}}, rampDuration)
+}
+
+type stepPacer struct {
+	Steps        []Step
+	RampDuration time.Duration
+
+	segments []segment
+}
+
+// segment is a part of the rate curve in which the rate changes linearly.
+type segment struct {
+	start     time.Duration
+	duration  time.Duration
+	startRate float64
+	// slope is the change of the rate per second
+	slope float64
+	// hits is the number of expected hits at the start of the segment
+	hits float64
+}
+
+func (s segment) rate(offset time.Duration) float64 {
+	return s.startRate + s.slope*offset.Seconds()
+}
+
+func (s segment) expectedHits(offset time.Duration) float64 {
+	x := offset.Seconds()
+	return s.hits + s.startRate*x + s.slope*x*x/2
+}
+
+// offsetOf returns the offset within the segment at which the given number of hits is expected.
+func (s segment) offsetOf(hits float64) time.Duration {
+	d := hits - s.hits
+	if d <= 0 {
+		return 0
+	}
+	// solves slope/2*x² + startRate*x = d in a numerically stable way
+	denominator := s.startRate + math.Sqrt(max(s.startRate*s.startRate+2*s.slope*d, 0))
+	if denominator <= 0 {
+		return s.duration
+	}
+	return time.Duration(2 * d / denominator * 1e9)
+}
+
+func (p stepPacer) validate() error {
+	if len(p.Steps) == 0 {
+		return errors.New("step pacer needs at least one step")
+	}
+	for _, step := range p.Steps {
+		if err := step.Rate.validate(); err != nil {
+			return err
+		}
+		if step.Hold < 0 {
+			return errors.New("hold duration can't be negative")
+		}
+	}
+	if p.RampDuration < 0 {
+		return errors.New("ramp duration can't be negative")
+	}
+	return nil
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p stepPacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	expectedHits := p.expectedHits(elapsed)
+	if hits == 0 || hits < uint64(expectedHits) {
+		// Running behind, send next hit immediately.
+		return 0
+	}
+
+	target := float64(hits + 1)
+	for i, s := range p.segments {
+		if i < len(p.segments)-1 && s.expectedHits(s.duration) < target {
+			continue
+		}
+		return max(s.start+s.offsetOf(target)-elapsed, 0)
+	}
+
+	return 0
+}
+
+func (p stepPacer) finished(hits uint64) bool {
+	if len(p.segments) == 0 {
+		return false
+	}
+	// the last segment holds its rate forever, so only a rate of zero ends the hits
+	last := p.segments[len(p.segments)-1]
+	return last.startRate <= 0 && last.slope == 0 && float64(hits+1) > last.hits
+}
+
+func (p stepPacer) Rate(elapsed time.Duration) float64 {
+	s, offset := p.segment(elapsed)
+	return s.rate(offset)
+}
+
+func (p stepPacer) expectedHits(t time.Duration) float64 {
+	if t < 0 {
+		return 0
+	}
+
+	s, offset := p.segment(t)
+	return s.expectedHits(offset)
+}
+
+// segment returns the segment the elapsed duration falls into and the offset within it.
+func (p stepPacer) segment(elapsed time.Duration) (segment, time.Duration) {
+	if len(p.segments) == 0 {
+		return segment{}, 0
+	}
+	elapsed = max(elapsed, 0)
+
+	for _, s := range p.segments {
+		if elapsed < s.start+s.duration {
+			return s, elapsed - s.start
+		}
+	}
+
+	last := p.segments[len(p.segments)-1]
+	return last, elapsed - last.start
+}
@@ -0,0 +1,81 @@
+package pacer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepPacer(t *testing.T) {
+	p := NewStepPacer([]Step{
+		{Rate: perSec(10), Hold: time.Second},
+		{Rate: perSec(20)},
+	}, 0)
+	if err := Validate(p); err != nil {
+		t.Fatal(err)
+	}
+
+	testHits(t, p, []hitsTest{
+		{elapsed: 0, hits: 0, rate: 10},
+		{elapsed: 500 * time.Millisecond, hits: 5, rate: 10},
+		{elapsed: time.Second, hits: 10, rate: 20},
+		{elapsed: 1500 * time.Millisecond, hits: 20, rate: 20},
+		{elapsed: time.Hour, hits: 10 + 20*3599, rate: 20},
+	})
+	testPace(t, p, []paceTest{
+		{elapsed: 0, hits: 0, want: 0},
+		{elapsed: 0, hits: 1, want: 200 * time.Millisecond},
+		// running behind
+		{elapsed: time.Second, hits: 9, want: 0},
+		// the next hit is due exactly at the boundary
+		{elapsed: 900 * time.Millisecond, hits: 9, want: 100 * time.Millisecond},
+		// the next hit is due after the boundary at the rate of the second step
+		{elapsed: 950 * time.Millisecond, hits: 10, want: 100 * time.Millisecond},
+		{elapsed: time.Second, hits: 10, want: 50 * time.Millisecond},
+		{elapsed: time.Second, hits: 12, want: 150 * time.Millisecond},
+	})
+}
+
+func TestStepPacerWithRamp(t *testing.T) {
+	// 10/s for 1s, ramp to 30/s within 2s, hold 30/s
+	p := NewStepPacer([]Step{
+		{Rate: perSec(10), Hold: time.Second},
+		{Rate: perSec(30)},
+	}, 2*time.Second)
+
+	testHits(t, p, []hitsTest{
+		{elapsed: time.Second, hits: 10, rate: 10},
+		{elapsed: 2 * time.Second, hits: 25, rate: 20},
+		{elapsed: 3 * time.Second, hits: 50, rate: 30},
+		{elapsed: 4 * time.Second, hits: 80, rate: 30},
+	})
+	testPace(t, p, []paceTest{
+		// 10x + 5x² = 15 hits after the start of the ramp at x = 1
+		{elapsed: 1500 * time.Millisecond, hits: 24, want: 500 * time.Millisecond},
+		{elapsed: 3 * time.Second, hits: 50, want: time.Second / 30},
+	})
+}
+
+func TestStepPacerValidate(t *testing.T) {
+	testInvalid(t, map[string]Pacer{
+		"no steps":      NewStepPacer(nil, 0),
+		"negative hold": NewStepPacer([]Step{{Rate: perSec(1), Hold: -time.Second}}, 0),
+		"negative ramp": NewStepPacer([]Step{{Rate: perSec(1)}, {Rate: perSec(2)}}, -time.Second),
+		"negative rate": NewStepPacer([]Step{{Rate: perSec(-1)}}, 0),
+	})
+}
+
+func TestStepPacerFinished(t *testing.T) {
+	down := NewStepPacer([]Step{{Rate: perSec(10), Hold: time.Second}, {Rate: perSec(0)}}, time.Second)
+	// 10 hits during the hold and 5 during the ramp down
+	for hits, want := range map[uint64]bool{0: false, 10: false, 14: false, 15: true, 20: true} {
+		if got := Finished(down, hits); got != want {
+			t.Errorf("Finished(%d) = %v, want %v", hits, got, want)
+		}
+	}
+	testPace(t, down, []paceTest{{elapsed: 3 * time.Second, hits: 15, want: never}})
+
+	held := NewStepPacer([]Step{{Rate: perSec(0), Hold: time.Second}, {Rate: perSec(10)}}, 0)
+	if Finished(held, 100) {
+		t.Error("a step pacer with a last rate > 0 finished")
+	}
+}
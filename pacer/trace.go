@@ -0,0 +1,298 @@
+package pacer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceFormat is the format of a trace file.
+type TraceFormat string
+
+const (
+	// TraceFormatTimestamps contains the timestamp of one request per line, either in RFC 3339
+	// format or as unix time in seconds with an optional fraction.
+	TraceFormatTimestamps TraceFormat = "timestamps"
+	// TraceFormatCounts contains the number of requests per second, one second per line.
+	TraceFormatCounts TraceFormat = "counts"
+)
+
+// Trace is a recorded arrival pattern of requests which can be replayed with NewTracePacer.
+type Trace struct {
+	// points contains the cumulative number of hits at the given offsets, hits are spread
+	// evenly in between two points
+	points []tracePoint
+	period time.Duration
+}
+
+type tracePoint struct {
+	offset time.Duration
+	hits   float64
+}
+
+// TraceFromTimestamps creates a trace which sends one hit at each timestamp relative to the
+// earliest one. When looping, the trace is repeated after the average interval between two
+// timestamps following the last one.
+func TraceFromTimestamps(timestamps []time.Time) Trace {
+	if len(timestamps) == 0 {
+		return Trace{}
+	}
+
+	sorted := slices.Clone(timestamps)
+	slices.SortFunc(sorted, func(a, b time.Time) int { return a.Compare(b) })
+
+	points := make([]tracePoint, 0, len(sorted)+1)
+	for i, timestamp := range sorted {
+		// the hit at the timestamp is due once its number of hits is expected
+		points = append(points, tracePoint{offset: timestamp.Sub(sorted[0]), hits: float64(i + 1)})
+	}
+
+	last := points[len(points)-1].offset
+	period := last
+	if len(sorted) > 1 {
+		period += last / time.Duration(len(sorted)-1)
+	}
+	points = append(points, tracePoint{offset: period, hits: float64(len(sorted))})
+
+	return Trace{points: points, period: period}
+}
+
+// TraceFromCounts creates a trace which sends the given number of hits in each interval,
+// spread evenly within the interval.
+func TraceFromCounts(counts []int, interval time.Duration) Trace {
+	if len(counts) == 0 {
+		return Trace{}
+	}
+
+	points := make([]tracePoint, 0, len(counts)+1)
+	points = append(points, tracePoint{})
+	hits := 0.0
+	for i, count := range counts {
+		hits += float64(max(count, 0))
+		points = append(points, tracePoint{offset: time.Duration(i+1) * interval, hits: hits})
+	}
+
+	return Trace{points: points, period: time.Duration(len(counts)) * interval}
+}
+
+// ReadTraceFile reads a trace from the file at path, see ReadTrace.
+func ReadTraceFile(path string, format TraceFormat) (Trace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Trace{}, err
+	}
+	defer file.Close()
+
+	return ReadTrace(file, format)
+}
+
+// ReadTrace reads a trace in the given format. Empty lines and lines starting with # are ignored.
+// If a line contains multiple comma separated fields, the timestamp is taken from the first and
+// the count from the last field.
+func ReadTrace(r io.Reader, format TraceFormat) (Trace, error) {
+	var timestamps []time.Time
+	var counts []int
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+
+		switch format {
+		case TraceFormatTimestamps:
+			timestamp, err := parseTimestamp(strings.TrimSpace(fields[0]))
+			if err != nil {
+				return Trace{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			timestamps = append(timestamps, timestamp)
+		case TraceFormatCounts:
+			count, err := strconv.Atoi(strings.TrimSpace(fields[len(fields)-1]))
+			if err != nil || count < 0 {
+				return Trace{}, fmt.Errorf("line %d: invalid count %q", line, fields[len(fields)-1])
+			}
+			counts = append(counts, count)
+		default:
+			return Trace{}, fmt.Errorf("unsupported trace format %q", format)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Trace{}, err
+	}
+
+	if format == TraceFormatCounts {
+		return TraceFromCounts(counts, time.Second), nil
+	}
+	return TraceFromTimestamps(timestamps), nil
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	if timestamp, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return timestamp, nil
+	}
+
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*1e9)), nil
+}
+
+// hits returns the number of hits expected at the offset within one pass of the trace.
+func (t Trace) hits(offset time.Duration) float64 {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].offset > offset })
+	if i == 0 {
+		return 0
+	}
+	if i == len(t.points) {
+		return t.points[len(t.points)-1].hits
+	}
+
+	from, to := t.points[i-1], t.points[i]
+	return from.hits + (to.hits-from.hits)*float64(offset-from.offset)/float64(to.offset-from.offset)
+}
+
+// offsetOf returns the offset within one pass of the trace at which the given number of hits
+// is expected. hits must not be more than the total of the trace.
+func (t Trace) offsetOf(hits float64) time.Duration {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].hits >= hits })
+	if i == 0 {
+		return t.points[0].offset
+	}
+	if i == len(t.points) {
+		return t.points[len(t.points)-1].offset
+	}
+
+	from, to := t.points[i-1], t.points[i]
+	return from.offset + time.Duration((hits-from.hits)/(to.hits-from.hits)*float64(to.offset-from.offset))
+}
+
+// rate returns the rate in hits per second at the offset within one pass of the trace.
+func (t Trace) rate(offset time.Duration) float64 {
+	i := sort.Search(len(t.points), func(i int) bool { return t.points[i].offset > offset })
+	if i == 0 || i == len(t.points) {
+		return 0
+	}
+
+	from, to := t.points[i-1], t.points[i]
+	return (to.hits - from.hits) / (to.offset - from.offset).Seconds()
+}
+
+// total returns the number of hits of one pass of the trace.
+func (t Trace) total() float64 {
+	if len(t.points) == 0 {
+		return 0
+	}
+	return t.points[len(t.points)-1].hits
+}
+
+// NewTracePacer creates a pacer which reproduces the arrival pattern of the trace. A speed of 2
+// replays the trace twice as fast, 0.5 at half the speed. If loop is true, the trace is repeated
+// once it has ended, otherwise no more hits are sent.
+func NewTracePacer(trace Trace, speed float64, loop bool) Pacer {
+	return tracePacer{
+		trace: trace,
+		speed: speed,
+		loop:  loop,
+	}
+}
+
+type tracePacer struct {
+	trace Trace
+	speed float64
+	loop  bool
+}
+
+func (p tracePacer) validate() error {
+	if p.trace.total() == 0 {
+		return errors.New("trace contains no hits")
+	}
+	if p.speed <= 0 || math.IsInf(p.speed, 0) || math.IsNaN(p.speed) {
+		return errors.New("speed must be > 0")
+	}
+	if p.loop && p.trace.period <= 0 {
+		return errors.New("trace needs to span a duration to be looped")
+	}
+	return nil
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p tracePacer) Pace(elapsed time.Duration, hits uint64) time.Duration {
+	if hits == 0 {
+		return 0
+	}
+
+	next := p.nextHitAt(hits)
+	if next == math.MaxInt64 {
+		return math.MaxInt64 - elapsed
+	}
+	return max(next-elapsed, 0)
+}
+
+func (p tracePacer) nextHitAt(hits uint64) time.Duration {
+	total := p.trace.total()
+	if total == 0 {
+		return math.MaxInt64
+	}
+
+	// like for the other pacers, the hit after the given number of hits is sent when one more hit is
+	// expected, the first hit is sent immediately
+	target := float64(hits + 1)
+	passes := 0.0
+	if p.loop {
+		// the last hit of a pass is part of it, not of the next one
+		passes = math.Ceil(target/total) - 1
+		target -= passes * total
+	} else if target > total {
+		// the trace has ended
+		return math.MaxInt64
+	}
+
+	offset := passes*float64(p.trace.period) + float64(p.trace.offsetOf(target))
+	next := offset / p.speed
+	if next >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(next)
+}
+
+func (p tracePacer) finished(hits uint64) bool {
+	// like in nextHitAt, the trace has ended once no hit is left for the next one
+	return !p.loop && float64(hits+1) > p.trace.total()
+}
+
+func (p tracePacer) Rate(elapsed time.Duration) float64 {
+	offset, _ := p.traceOffset(elapsed)
+	return p.trace.rate(offset) * p.speed
+}
+
+func (p tracePacer) expectedHits(t time.Duration) float64 {
+	if t < 0 {
+		return 0
+	}
+
+	offset, passes := p.traceOffset(t)
+	return passes*p.trace.total() + p.trace.hits(offset)
+}
+
+// traceOffset converts the elapsed duration to an offset within the trace and the number of completed passes.
+func (p tracePacer) traceOffset(elapsed time.Duration) (time.Duration, float64) {
+	offset := time.Duration(float64(max(elapsed, 0)) * p.speed)
+	if !p.loop || p.trace.period <= 0 {
+		return offset, 0
+	}
+	return offset % p.trace.period, float64(offset / p.trace.period)
+}
@@ -0,0 +1,158 @@
+package pacer
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracePacer(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// the trace repeats 1.5s, the average interval, after the last timestamp
+	trace := TraceFromTimestamps([]time.Time{start.Add(3 * time.Second), start, start.Add(time.Second)})
+
+	tests := []struct {
+		name  string
+		speed float64
+		loop  bool
+		want  []time.Duration
+	}{
+		{name: "once", speed: 1, want: []time.Duration{0, time.Second, 3 * time.Second, never}},
+		{name: "loop", speed: 1, loop: true, want: []time.Duration{0, time.Second, 3 * time.Second, 4500 * time.Millisecond, 5500 * time.Millisecond, 7500 * time.Millisecond}},
+		{name: "fast", speed: 2, want: []time.Duration{0, 500 * time.Millisecond, 1500 * time.Millisecond, never}},
+		{name: "slow", speed: 0.5, loop: true, want: []time.Duration{0, 2 * time.Second, 6 * time.Second, 9 * time.Second}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewTracePacer(trace, tt.speed, tt.loop)
+			if err := Validate(p); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tt.want {
+				// the offset of the hit after i hits
+				next := NextHitAt(p, uint64(i), 0)
+				if want == never {
+					if next != never {
+						t.Errorf("hit %d is at %v, expected no more hits", i+1, next)
+					}
+					continue
+				}
+				if !nearDuration(next, want) {
+					t.Errorf("NextHitAt(%d) = %v, want %v", i, next, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTracePacerExpectedHits(t *testing.T) {
+	trace := TraceFromCounts([]int{10, 0, 20}, time.Second)
+
+	testHits(t, NewTracePacer(trace, 1, false), []hitsTest{
+		{elapsed: 500 * time.Millisecond, hits: 5, rate: 10},
+		{elapsed: time.Second, hits: 10, rate: 0},
+		{elapsed: 2 * time.Second, hits: 10, rate: 20},
+		{elapsed: 2500 * time.Millisecond, hits: 20, rate: 20},
+		{elapsed: 3 * time.Second, hits: 30, rate: 0},
+		{elapsed: 4 * time.Second, hits: 30, rate: 0},
+	})
+	testPace(t, NewTracePacer(trace, 1, false), []paceTest{
+		{elapsed: 2 * time.Second, hits: 10, want: 50 * time.Millisecond},
+		{elapsed: 3 * time.Second, hits: 30, want: never},
+	})
+	if Finished(NewTracePacer(trace, 1, false), 29) || !Finished(NewTracePacer(trace, 1, false), 30) {
+		t.Error("the trace should end after its 30 hits")
+	}
+
+	loop := NewTracePacer(trace, 1, true)
+	testHits(t, loop, []hitsTest{
+		{elapsed: 500 * time.Millisecond, hits: 5, rate: 10},
+		{elapsed: time.Second, hits: 10, rate: 0},
+		{elapsed: 2 * time.Second, hits: 10, rate: 20},
+		{elapsed: 2500 * time.Millisecond, hits: 20, rate: 20},
+		{elapsed: 3 * time.Second, hits: 30, rate: 10},
+		{elapsed: 4 * time.Second, hits: 40, rate: 0},
+	})
+	testPace(t, loop, []paceTest{
+		{elapsed: 0, hits: 0, want: 0},
+		{elapsed: 0, hits: 1, want: 200 * time.Millisecond},
+		// nothing is sent in the second interval
+		{elapsed: 950 * time.Millisecond, hits: 10, want: 1100 * time.Millisecond},
+		{elapsed: 2 * time.Second, hits: 10, want: 50 * time.Millisecond},
+		// the next hit is due at the start of the next pass
+		{elapsed: 3 * time.Second, hits: 30, want: 100 * time.Millisecond},
+	})
+	if Finished(loop, 1000) {
+		t.Error("a looped trace finished")
+	}
+}
+
+func TestReadTrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  TraceFormat
+		input   string
+		hits    float64
+		period  time.Duration
+		wantErr bool
+	}{
+		{
+			name:   "unix timestamps",
+			format: TraceFormatTimestamps,
+			input:  "# requests\n1700000000.5\n\n1700000000,GET /\n1700000001\n",
+			hits:   3,
+			period: 1500 * time.Millisecond,
+		},
+		{
+			name:   "RFC 3339 timestamps",
+			format: TraceFormatTimestamps,
+			input:  "2024-01-01T00:00:00Z\n2024-01-01T00:00:02.5Z\n",
+			hits:   2,
+			period: 5 * time.Second,
+		},
+		{
+			name:   "counts",
+			format: TraceFormatCounts,
+			input:  "0,5\n1, 10\n# pause\n0\n",
+			hits:   15,
+			period: 3 * time.Second,
+		},
+		{name: "invalid timestamp", format: TraceFormatTimestamps, input: "yesterday\n", wantErr: true},
+		{name: "negative count", format: TraceFormatCounts, input: "-1\n", wantErr: true},
+		{name: "invalid count", format: TraceFormatCounts, input: "many\n", wantErr: true},
+		{name: "unknown format", format: "csv", input: "1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := ReadTrace(strings.NewReader(tt.input), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadTrace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if trace.total() != tt.hits {
+				t.Errorf("the trace contains %v hits, expected %v", trace.total(), tt.hits)
+			}
+			if trace.period != tt.period {
+				t.Errorf("the period of the trace is %v, expected %v", trace.period, tt.period)
+			}
+		})
+	}
+}
+
+func TestTracePacerValidate(t *testing.T) {
+	trace := TraceFromCounts([]int{1}, time.Second)
+
+	testInvalid(t, map[string]Pacer{
+		"empty trace":           NewTracePacer(Trace{}, 1, false),
+		"no hits":               NewTracePacer(TraceFromCounts([]int{0, 0}, time.Second), 1, false),
+		"no speed":              NewTracePacer(trace, 0, false),
+		"infinite speed":        NewTracePacer(trace, math.Inf(1), false),
+		"loop without a period": NewTracePacer(TraceFromTimestamps([]time.Time{time.Now()}), 1, true),
+	})
+}
@@ -0,0 +1,337 @@
+package goload_prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/scayle/goload"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds (in seconds) of the latency histogram buckets.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Exporter records metrics of all results and exposes them in the Prometheus text format.
+//
+// It can be used as an http.Handler on an existing server or be started with WithExporter.
+type Exporter struct {
+	addr            string
+	buckets         []float64
+	errorClassifier func(err error) string
+
+	loadTest atomic.Pointer[goload.LoadTest]
+	server   *http.Server
+
+	mu        sync.RWMutex
+	executors map[string]*executorMetrics
+	// scenarios contains the metrics of whole journeys, their steps are recorded as executors
+	scenarios map[string]*executorMetrics
+}
+
+type executorMetrics struct {
+	requests atomic.Uint64
+
+	mu      sync.Mutex
+	errors  map[string]uint64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+type ExporterOption func(exporter *Exporter)
+
+// WithBuckets overrides the upper bounds (in seconds) of the latency histogram buckets.
+func WithBuckets(buckets ...float64) ExporterOption {
+	return func(exporter *Exporter) {
+		exporter.buckets = buckets
+	}
+}
+
+// WithErrorClassifier overrides how errors are mapped to the `class` label of the error counter.
+func WithErrorClassifier(classifier func(err error) string) ExporterOption {
+	return func(exporter *Exporter) {
+		exporter.errorClassifier = classifier
+	}
+}
+
+func NewExporter(opts ...ExporterOption) *Exporter {
+	exporter := &Exporter{
+		buckets:         DefaultBuckets,
+		errorClassifier: ClassifyError,
+		executors:       map[string]*executorMetrics{},
+		scenarios:       map[string]*executorMetrics{},
+	}
+
+	for _, opt := range opts {
+		opt(exporter)
+	}
+
+	exporter.buckets = append([]float64(nil), exporter.buckets...)
+	sort.Float64s(exporter.buckets)
+
+	return exporter
+}
+
+// WithExporter serves the metrics of the load test on `addr` under `/metrics` while it is running.
+func WithExporter(addr string, opts ...ExporterOption) goload.LoadTestOption {
+	exporter := NewExporter(opts...)
+	exporter.addr = addr
+
+	return func(options *goload.LoadTestOptions) {
+		goload.WithAdditionalResultHandler(exporter.HandleResult)(options)
+		goload.WithHook(exporter)(options)
+	}
+}
+
+// ClassifyError maps errors to a small set of classes: timeout, canceled, network and other.
+func ClassifyError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// HandleResult records a single result. It can be registered with goload.WithAdditionalResultHandler.
+func (e *Exporter) HandleResult(lt *goload.LoadTest, result *goload.Result) {
+	e.loadTest.Store(lt)
+
+	// a journey is exported as its scenario, its steps are counted as requests
+	var metrics *executorMetrics
+	if result.Journey {
+		metrics = e.metrics(e.scenarios, result.Scenario)
+	} else {
+		metrics = e.metrics(e.executors, result.Identifier)
+	}
+	metrics.requests.Add(1)
+
+	latency := result.Latency.Seconds()
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	if result.Err != nil {
+		metrics.errors[e.errorClassifier(result.Err)]++
+	}
+	for i, bound := range e.buckets {
+		if latency <= bound {
+			metrics.buckets[i]++
+		}
+	}
+	metrics.count++
+	metrics.sum += latency
+}
+
+// metrics returns the metrics with the given name in m, which is either executors or scenarios.
+func (e *Exporter) metrics(m map[string]*executorMetrics, name string) *executorMetrics {
+	e.mu.RLock()
+	metrics, ok := m[name]
+	e.mu.RUnlock()
+	if ok {
+		return metrics
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if metrics, ok := m[name]; ok {
+		return metrics
+	}
+	metrics = &executorMetrics{
+		errors:  map[string]uint64{},
+		buckets: make([]uint64, len(e.buckets)),
+	}
+	m[name] = metrics
+
+	return metrics
+}
+
+// Start implements goload.Hook and starts the metrics server if an address was configured.
+func (e *Exporter) Start(lt *goload.LoadTest) error {
+	e.loadTest.Store(lt)
+	if e.addr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", e.addr)
+	if err != nil {
+		return fmt.Errorf("can't start prometheus exporter: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	e.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := e.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("prometheus exporter failed")
+		}
+	}()
+
+	return nil
+}
+
+// Finish implements goload.Hook and stops the metrics server.
+func (e *Exporter) Finish(_ *goload.LoadTest, _ *goload.Report) error {
+	if e.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return e.server.Shutdown(ctx)
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := e.Write(w); err != nil {
+		log.Error().Err(err).Msg("failed to write metrics")
+	}
+}
+
+// Write writes all metrics in the Prometheus text format.
+func (e *Exporter) Write(w io.Writer) error {
+	var b strings.Builder
+
+	e.writeMetrics(&b, e.executors, executorFamily)
+	e.mu.RLock()
+	hasScenarios := len(e.scenarios) > 0
+	e.mu.RUnlock()
+	if hasScenarios {
+		e.writeMetrics(&b, e.scenarios, scenarioFamily)
+	}
+
+	if lt := e.loadTest.Load(); lt != nil {
+		if lt.Pacer != nil {
+			writeHeader(&b, "goload_pacer_rate", "gauge", "Current rate of the pacer in hits per second.")
+			fmt.Fprintf(&b, "goload_pacer_rate %s\n", formatFloat(lt.Pacer.Rate(lt.Elapsed())))
+		}
+
+		writeHeader(&b, "goload_workers", "gauge", "Number of running workers.")
+		workers := lt.WorkerStats()
+		fmt.Fprintf(&b, "goload_workers %d\n", workers.Live)
+		writeHeader(&b, "goload_workers_busy", "gauge", "Number of workers which are executing a hit.")
+		fmt.Fprintf(&b, "goload_workers_busy %d\n", workers.Busy)
+		writeHeader(&b, "goload_workers_idle", "gauge", "Number of workers which are waiting for the next hit.")
+		fmt.Fprintf(&b, "goload_workers_idle %d\n", workers.Idle)
+
+		if lt.Pacer != nil {
+			saturation := lt.Runner.Saturation()
+			writeHeader(&b, "goload_late_ticks_total", "counter", "Number of hits sent later than the threshold because all workers were busy.")
+			fmt.Fprintf(&b, "goload_late_ticks_total %d\n", saturation.LateTicks)
+			writeHeader(&b, "goload_dropped_ticks_total", "counter", "Number of hits dropped because all workers were busy.")
+			fmt.Fprintf(&b, "goload_dropped_ticks_total %d\n", saturation.DroppedTicks)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricFamily contains the metric names of either executors or scenarios.
+type metricFamily struct {
+	label    string
+	requests string
+	errors   string
+	duration string
+	// subject is what is counted, used in the help texts
+	subject string
+}
+
+var (
+	executorFamily = metricFamily{
+		label:    "executor",
+		requests: "goload_requests_total",
+		errors:   "goload_errors_total",
+		duration: "goload_request_duration_seconds",
+		subject:  "requests",
+	}
+	scenarioFamily = metricFamily{
+		label:    "scenario",
+		requests: "goload_journeys_total",
+		errors:   "goload_journey_errors_total",
+		duration: "goload_journey_duration_seconds",
+		subject:  "journeys of scenarios",
+	}
+)
+
+// writeMetrics writes the counters and latency histograms of the metrics in m labeled with their name.
+func (e *Exporter) writeMetrics(b *strings.Builder, m map[string]*executorMetrics, family metricFamily) {
+	e.mu.RLock()
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	e.mu.RUnlock()
+	sort.Strings(names)
+
+	label, requestsName, errorsName, durationName := family.label, family.requests, family.errors, family.duration
+
+	writeHeader(b, requestsName, "counter", fmt.Sprintf("Number of executed %s.", family.subject))
+	for _, name := range names {
+		metrics := e.metrics(m, name)
+		fmt.Fprintf(b, "%s{%s=%s} %d\n", requestsName, label, quote(name), metrics.requests.Load())
+	}
+
+	writeHeader(b, errorsName, "counter", fmt.Sprintf("Number of failed %s by error class.", family.subject))
+	for _, name := range names {
+		metrics := e.metrics(m, name)
+		metrics.mu.Lock()
+		classes := make([]string, 0, len(metrics.errors))
+		for class := range metrics.errors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(b, "%s{%s=%s,class=%s} %d\n", errorsName, label, quote(name), quote(class), metrics.errors[class])
+		}
+		metrics.mu.Unlock()
+	}
+
+	writeHeader(b, durationName, "histogram", fmt.Sprintf("Latency of the executed %s.", family.subject))
+	for _, name := range names {
+		metrics := e.metrics(m, name)
+		metrics.mu.Lock()
+		for i, bound := range e.buckets {
+			fmt.Fprintf(b, "%s_bucket{%s=%s,le=%s} %d\n", durationName, label, quote(name), quote(formatFloat(bound)), metrics.buckets[i])
+		}
+		fmt.Fprintf(b, "%s_bucket{%s=%s,le=\"+Inf\"} %d\n", durationName, label, quote(name), metrics.count)
+		fmt.Fprintf(b, "%s_sum{%s=%s} %s\n", durationName, label, quote(name), formatFloat(metrics.sum))
+		fmt.Fprintf(b, "%s_count{%s=%s} %d\n", durationName, label, quote(name), metrics.count)
+		metrics.mu.Unlock()
+	}
+}
+
+func writeHeader(b *strings.Builder, name string, metricType string, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, metricType)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(value string) string {
+	return `"` + labelValueReplacer.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
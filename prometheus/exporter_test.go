@@ -0,0 +1,124 @@
+package goload_prometheus
+
+import (
+	"context"
+	"errors"
+	"github.com/scayle/goload"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExporterWrite(t *testing.T) {
+	exporter := NewExporter(WithBuckets(0.1, 0.01))
+	exporter.HandleResult(nil, &goload.Result{Identifier: "home", Latency: 5 * time.Millisecond})
+	exporter.HandleResult(nil, &goload.Result{Identifier: "home", Latency: 50 * time.Millisecond, Err: context.DeadlineExceeded})
+	exporter.HandleResult(nil, &goload.Result{Identifier: `say "hi"`, Latency: time.Second, Err: errors.New("boom")})
+	exporter.HandleResult(nil, &goload.Result{Scenario: "checkout", Journey: true, Latency: 200 * time.Millisecond})
+
+	var b strings.Builder
+	if err := exporter.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP goload_requests_total Number of executed requests.
+# TYPE goload_requests_total counter
+goload_requests_total{executor="home"} 2
+goload_requests_total{executor="say \"hi\""} 1
+# HELP goload_errors_total Number of failed requests by error class.
+# TYPE goload_errors_total counter
+goload_errors_total{executor="home",class="timeout"} 1
+goload_errors_total{executor="say \"hi\"",class="other"} 1
+# HELP goload_request_duration_seconds Latency of the executed requests.
+# TYPE goload_request_duration_seconds histogram
+goload_request_duration_seconds_bucket{executor="home",le="0.01"} 1
+goload_request_duration_seconds_bucket{executor="home",le="0.1"} 2
+goload_request_duration_seconds_bucket{executor="home",le="+Inf"} 2
+goload_request_duration_seconds_sum{executor="home"} 0.055
+goload_request_duration_seconds_count{executor="home"} 2
+goload_request_duration_seconds_bucket{executor="say \"hi\"",le="0.01"} 0
+goload_request_duration_seconds_bucket{executor="say \"hi\"",le="0.1"} 0
+goload_request_duration_seconds_bucket{executor="say \"hi\"",le="+Inf"} 1
+goload_request_duration_seconds_sum{executor="say \"hi\""} 1
+goload_request_duration_seconds_count{executor="say \"hi\""} 1
+# HELP goload_journeys_total Number of executed journeys of scenarios.
+# TYPE goload_journeys_total counter
+goload_journeys_total{scenario="checkout"} 1
+# HELP goload_journey_errors_total Number of failed journeys of scenarios by error class.
+# TYPE goload_journey_errors_total counter
+# HELP goload_journey_duration_seconds Latency of the executed journeys of scenarios.
+# TYPE goload_journey_duration_seconds histogram
+goload_journey_duration_seconds_bucket{scenario="checkout",le="0.01"} 0
+goload_journey_duration_seconds_bucket{scenario="checkout",le="0.1"} 0
+goload_journey_duration_seconds_bucket{scenario="checkout",le="+Inf"} 1
+goload_journey_duration_seconds_sum{scenario="checkout"} 0.2
+goload_journey_duration_seconds_count{scenario="checkout"} 1
+`
+	if b.String() != expected {
+		t.Errorf("unexpected metrics:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+	checkExpositionFormat(t, b.String())
+}
+
+func TestExporterServeHTTP(t *testing.T) {
+	exporter := NewExporter()
+	exporter.HandleResult(nil, &goload.Result{Identifier: "home", Latency: 30 * time.Millisecond})
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	checkExpositionFormat(t, recorder.Body.String())
+}
+
+var (
+	commentLine = regexp.MustCompile(`^# (HELP|TYPE) ([a-zA-Z_:][a-zA-Z0-9_:]*) (.+)$`)
+	sampleLine  = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{(?:[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*",?)*\})? (\S+)$`)
+)
+
+// checkExpositionFormat checks that every sample is a valid line of the Prometheus text format
+// and belongs to the family declared by the preceding TYPE line.
+func checkExpositionFormat(t *testing.T, text string) {
+	t.Helper()
+
+	if !strings.HasSuffix(text, "\n") {
+		t.Error("metrics must end with a line feed")
+	}
+
+	family, familyType := "", ""
+	types := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if match := commentLine.FindStringSubmatch(line); match != nil {
+			if match[1] == "TYPE" {
+				if types[match[2]] {
+					t.Errorf("metric family %s declared twice", match[2])
+				}
+				types[match[2]] = true
+				family, familyType = match[2], match[3]
+			}
+			continue
+		}
+
+		match := sampleLine.FindStringSubmatch(line)
+		if match == nil {
+			t.Errorf("invalid sample %q", line)
+			continue
+		}
+		name := match[1]
+		if familyType == "histogram" {
+			name = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+		}
+		if name != family {
+			t.Errorf("sample %q doesn't belong to the family %s", line, family)
+		}
+		if _, err := strconv.ParseFloat(match[3], 64); err != nil {
+			t.Errorf("invalid value in %q: %v", line, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package goload
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultFilter restricts which results of a results file are included in a report.
+type ResultFilter struct {
+	// From and To restrict the results to a time window relative to the first result.
+	// Zero values are unbounded.
+	From time.Duration
+	To   time.Duration
+	// After and Before restrict the results to an absolute time window. Zero values are unbounded.
+	After  time.Time
+	Before time.Time
+	// Executors restricts the results to the given executor identifiers or groups.
+	Executors []string
+}
+
+func (f ResultFilter) relative() bool {
+	return f.From > 0 || f.To > 0
+}
+
+func (f ResultFilter) matches(result *Result, firstTimestamp time.Time) bool {
+	if len(f.Executors) > 0 && !slices.Contains(f.Executors, result.Identifier) && !slices.ContainsFunc(result.groups(), func(group string) bool {
+		return slices.Contains(f.Executors, group)
+	}) {
+		return false
+	}
+	if !f.After.IsZero() && result.Timestamp.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !result.Timestamp.Before(f.Before) {
+		return false
+	}
+
+	offset := result.Timestamp.Sub(firstTimestamp)
+	if f.From > 0 && offset < f.From {
+		return false
+	}
+	if f.To > 0 && offset >= f.To {
+		return false
+	}
+	return true
+}
+
+// DetectResultFormat returns the format of a results file based on its extension.
+func DetectResultFormat(path string) ResultFormat {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ResultFormatCSV
+	}
+	return ResultFormatJSONL
+}
+
+// ReportFromFile rebuilds the report of a run from a results file written with WithResultsFile.
+//
+// As the pacer of the original run is unknown, the report doesn't contain expected rates.
+func ReportFromFile(path string, format ResultFormat, filter ResultFilter) (*Report, error) {
+	var firstTimestamp time.Time
+	if filter.relative() {
+		err := readResultsFile(path, format, func(result *Result) error {
+			if firstTimestamp.IsZero() || result.Timestamp.Before(firstTimestamp) {
+				firstTimestamp = result.Timestamp
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aggregator := newResultAggregator()
+	var startedAt, endedAt time.Time
+	err := readResultsFile(path, format, func(result *Result) error {
+		if !filter.matches(result, firstTimestamp) {
+			return nil
+		}
+
+		aggregator.resultAggregationHandler(nil, result)
+		if startedAt.IsZero() || result.Timestamp.Before(startedAt) {
+			startedAt = result.Timestamp
+		}
+		if end := result.Timestamp.Add(result.Latency); end.After(endedAt) {
+			endedAt = end
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregator.report(startedAt, endedAt.Sub(startedAt), 0, nil), nil
+}
+
+func readResultsFile(path string, format ResultFormat, fn func(result *Result) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ReadResults(file, format, fn)
+}
+
+// ReadResults decodes all results from r and calls fn for each of them.
+func ReadResults(r io.Reader, format ResultFormat, fn func(result *Result) error) error {
+	switch format {
+	case ResultFormatJSONL:
+		return readJSONLResults(r, fn)
+	case ResultFormatCSV:
+		return readCSVResults(r, fn)
+	default:
+		return fmt.Errorf("unsupported result format %q", format)
+	}
+}
+
+func readJSONLResults(r io.Reader, fn func(result *Result) error) error {
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var record resultRecord
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("invalid result %d: %w", line, err)
+		}
+
+		if err := fn(record.result()); err != nil {
+			return err
+		}
+	}
+}
+
+func readCSVResults(r io.Reader, fn func(result *Result) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid csv header: %w", err)
+	}
+	if !slices.Equal(header, csvHeader) {
+		return fmt.Errorf("invalid csv header: %v", header)
+	}
+
+	for {
+		row, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		record, err := decodeCSVRecord(row)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return fmt.Errorf("invalid result in line %d: %w", line, err)
+		}
+
+		if err := fn(record.result()); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeCSVRecord(row []string) (resultRecord, error) {
+	timestamp, err := time.Parse(time.RFC3339Nano, row[2])
+	if err != nil {
+		return resultRecord{}, err
+	}
+	latency, err := strconv.ParseInt(row[3], 10, 64)
+	if err != nil {
+		return resultRecord{}, err
+	}
+
+	record := resultRecord{
+		Identifier: row[0],
+		Group:      row[1],
+		Timestamp:  timestamp,
+		LatencyNs:  latency,
+		Error:      row[4],
+		Scenario:   row[7],
+	}
+	if record.DelayNs, err = strconv.ParseInt(row[6], 10, 64); err != nil {
+		return resultRecord{}, err
+	}
+	if record.Journey, err = strconv.ParseBool(row[8]); err != nil {
+		return resultRecord{}, err
+	}
+	if row[9] != "" {
+		if err := json.Unmarshal([]byte(row[9]), &record.Groups); err != nil {
+			return resultRecord{}, err
+		}
+	}
+	if row[5] != "" {
+		if err := json.Unmarshal([]byte(row[5]), &record.AdditionalData); err != nil {
+			return resultRecord{}, err
+		}
+	}
+
+	return record, nil
+}
+
+func (r resultRecord) result() *Result {
+	result := &Result{
+		Identifier:     r.Identifier,
+		Group:          r.Group,
+		Groups:         r.Groups,
+		Scenario:       r.Scenario,
+		Journey:        r.Journey,
+		Timestamp:      r.Timestamp,
+		Intended:       r.Timestamp.Add(-time.Duration(r.DelayNs)),
+		Latency:        time.Duration(r.LatencyNs),
+		AdditionalData: r.AdditionalData,
+	}
+	if r.Error != "" {
+		result.Err = errors.New(r.Error)
+	}
+	return result
+}
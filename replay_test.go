@@ -0,0 +1,96 @@
+package goload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadResultsRoundTrip(t *testing.T) {
+	for _, format := range []ResultFormat{ResultFormatJSONL, ResultFormatCSV} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			want := testResults()
+			path := writeResultsFile(t, format, want)
+
+			var got []*Result
+			err := readResultsFile(path, format, func(result *Result) error {
+				got = append(got, result)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("read %d results, expected %d", len(got), len(want))
+			}
+
+			for i := range want {
+				if got[i].Identifier != want[i].Identifier || got[i].Group != want[i].Group ||
+					got[i].Scenario != want[i].Scenario || got[i].Journey != want[i].Journey {
+					t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+				}
+				if !reflect.DeepEqual(got[i].groups(), want[i].groups()) {
+					t.Errorf("result %d has the groups %v, want %v", i, got[i].groups(), want[i].groups())
+				}
+				if !got[i].Timestamp.Equal(want[i].Timestamp) || got[i].Latency != want[i].Latency {
+					t.Errorf("result %d was sent at %v for %v, want %v for %v", i, got[i].Timestamp, got[i].Latency, want[i].Timestamp, want[i].Latency)
+				}
+				if got[i].CorrectedLatency() != want[i].CorrectedLatency() {
+					t.Errorf("result %d has a corrected latency of %v, want %v", i, got[i].CorrectedLatency(), want[i].CorrectedLatency())
+				}
+				// failures without an error message stay failures
+				if (got[i].Err != nil) != (want[i].Err != nil) {
+					t.Errorf("result %d has the error %v, want %v", i, got[i].Err, want[i].Err)
+				}
+				if !reflect.DeepEqual(got[i].AdditionalData, want[i].AdditionalData) {
+					t.Errorf("result %d has the data %v, want %v", i, got[i].AdditionalData, want[i].AdditionalData)
+				}
+			}
+		})
+	}
+}
+
+func TestReportFromFile(t *testing.T) {
+	path := writeResultsFile(t, ResultFormatJSONL, testResults())
+
+	tests := []struct {
+		name     string
+		filter   ResultFilter
+		requests int64
+		failures int64
+	}{
+		// journeys are reported as scenarios, not as requests
+		{name: "all", requests: 3, failures: 1},
+		{name: "relative window", filter: ResultFilter{From: time.Second, To: 3 * time.Second}, requests: 2, failures: 1},
+		{name: "executor", filter: ResultFilter{Executors: []string{"get"}}, requests: 1},
+		{name: "nested group", filter: ResultFilter{Executors: []string{"inner"}}, requests: 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ReportFromFile(path, ResultFormatJSONL, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Total.Requests != tt.requests || report.Total.Failures != tt.failures {
+				t.Errorf("the report contains %d requests and %d failures, expected %d and %d",
+					report.Total.Requests, report.Total.Failures, tt.requests, tt.failures)
+			}
+		})
+	}
+}
+
+func TestReadResultsCSVHeader(t *testing.T) {
+	for _, header := range []string{
+		"identifier,group,timestamp,latency_ns,error,additional_data\n",
+		"identifier,group,timestamp\n",
+		"",
+	} {
+		err := ReadResults(strings.NewReader(header), ResultFormatCSV, func(result *Result) error { return nil })
+		if err == nil {
+			t.Errorf("expected an error for the header %q", header)
+		}
+	}
+}
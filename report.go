@@ -0,0 +1,429 @@
+package goload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/scayle/goload/utils/histogram"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// reportedPercentiles are the latency percentiles which are shown in reports.
+var reportedPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+// Report summarizes the results of a load test run.
+type Report struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+
+	// Total contains the statistics over all executors.
+	Total Stats `json:"total"`
+	// Executors contains the statistics per executor identifier in alphabetical order.
+	Executors []Stats `json:"executors"`
+	// Groups contains the statistics per executor group in alphabetical order.
+	Groups []Stats `json:"groups,omitempty"`
+	// Scenarios contains the statistics of the whole journeys per scenario in alphabetical order.
+	Scenarios []Stats `json:"scenarios,omitempty"`
+
+	// Thresholds contains the outcome of all thresholds checked at the end of the run.
+	Thresholds []ThresholdResult `json:"thresholds,omitempty"`
+
+	// Timeline contains the results sampled in a fixed interval while the load test was running.
+	Timeline []TimelineSample `json:"timeline,omitempty"`
+
+	// VirtualUsers contains the iterations of the virtual users if the closed model was used.
+	VirtualUsers *VirtualUserStats `json:"virtual_users,omitempty"`
+
+	// Capacity contains the outcome of the capacity search if it was used.
+	Capacity *CapacityResult `json:"capacity,omitempty"`
+
+	// Saturation contains the hits which couldn't be sent on time if a pacer was used.
+	Saturation *SaturationStats `json:"saturation,omitempty"`
+}
+
+// Stats contains the aggregated results of a single executor, group or the whole run.
+type Stats struct {
+	Name      string `json:"name"`
+	Requests  int64  `json:"requests"`
+	Successes int64  `json:"successes"`
+	Failures  int64  `json:"failures"`
+	// ErrorRate is the share of failed requests (0-1).
+	ErrorRate float64 `json:"error_rate"`
+	// Rate is the achieved throughput in hits per second.
+	Rate float64 `json:"rate"`
+	// ExpectedRate is the throughput in hits per second the pacer was expected to achieve.
+	ExpectedRate float64 `json:"expected_rate"`
+	// Latency is the service time from sending a hit until it has completed.
+	Latency LatencyDistribution `json:"latency"`
+	// CorrectedLatency is the response time from the intended send time of a hit until it has
+	// completed. It includes delays of the load generator, which hide an overloaded target otherwise.
+	CorrectedLatency LatencyDistribution `json:"corrected_latency"`
+}
+
+type LatencyDistribution struct {
+	Min         time.Duration `json:"min_ns"`
+	Mean        time.Duration `json:"mean_ns"`
+	Max         time.Duration `json:"max_ns"`
+	Percentiles []Percentile  `json:"percentiles"`
+}
+
+type Percentile struct {
+	Percentile float64       `json:"percentile"`
+	Value      time.Duration `json:"value_ns"`
+}
+
+// Executor returns the statistics of the executor with the given name.
+func (r *Report) Executor(name string) (Stats, bool) {
+	for _, stats := range r.Executors {
+		if stats.Name == name {
+			return stats, true
+		}
+	}
+	return Stats{}, false
+}
+
+// Group returns the statistics of the group with the given name.
+func (r *Report) Group(name string) (Stats, bool) {
+	for _, stats := range r.Groups {
+		if stats.Name == name {
+			return stats, true
+		}
+	}
+	return Stats{}, false
+}
+
+// Scenario returns the statistics of the journeys of the scenario with the given name.
+func (r *Report) Scenario(name string) (Stats, bool) {
+	for _, stats := range r.Scenarios {
+		if stats.Name == name {
+			return stats, true
+		}
+	}
+	return Stats{}, false
+}
+
+// Percentile returns the latency at the given percentile or zero if it wasn't reported.
+func (d LatencyDistribution) Percentile(p float64) time.Duration {
+	for _, percentile := range d.Percentiles {
+		if percentile.Percentile == p {
+			return percentile.Value
+		}
+	}
+	return 0
+}
+
+// WriteTable renders the report as a human-readable table.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header, rows := r.tableRows()
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "duration: %s\n", r.Duration.Round(time.Millisecond)); err != nil {
+		return err
+	}
+	if r.Capacity != nil {
+		if _, err := fmt.Fprintf(w, "capacity: %s\n", formatCapacity(r.Capacity)); err != nil {
+			return err
+		}
+	}
+	if r.Saturation != nil && r.Saturation.Saturated() {
+		if _, err := fmt.Fprintf(w, "WARNING: %s\n", formatSaturation(r.Saturation)); err != nil {
+			return err
+		}
+	}
+	if users := r.VirtualUsers; users != nil {
+		_, err := fmt.Fprintf(w, "virtual users: %d peak, %d iterations, %.2f/s min, %.2f/s mean, %.2f/s max per user\n",
+			users.Peak, users.Iterations, users.MinRate, users.MeanRate, users.MaxRate)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(r.Thresholds) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "thresholds:"); err != nil {
+		return err
+	}
+	for _, result := range r.Thresholds {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		if _, err := fmt.Fprintf(w, "  %s %s (actual: %s)\n", status, result.Description, result.Actual); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableRows returns the header and a row for each executor, group and the total.
+func (r *Report) tableRows() ([]string, [][]string) {
+	header := []string{"NAME", "REQUESTS", "SUCCESS", "FAILED", "ERRORS", "RATE", "EXPECTED"}
+	for _, p := range reportedPercentiles {
+		header = append(header, "P"+formatPercentile(p))
+	}
+	header = append(header, "MAX", "CORRECTED P99")
+
+	row := func(name string, stats Stats) []string {
+		expectedRate := "-"
+		if stats.ExpectedRate > 0 {
+			expectedRate = fmt.Sprintf("%.2f/s", stats.ExpectedRate)
+		}
+		row := []string{
+			name,
+			strconv.FormatInt(stats.Requests, 10),
+			strconv.FormatInt(stats.Successes, 10),
+			strconv.FormatInt(stats.Failures, 10),
+			fmt.Sprintf("%.2f%%", stats.ErrorRate*100),
+			fmt.Sprintf("%.2f/s", stats.Rate),
+			expectedRate,
+		}
+		for _, p := range reportedPercentiles {
+			row = append(row, roundLatency(stats.Latency.Percentile(p)).String())
+		}
+		return append(row, roundLatency(stats.Latency.Max).String(), roundLatency(stats.CorrectedLatency.Percentile(99)).String())
+	}
+
+	rows := make([][]string, 0, len(r.Executors)+len(r.Groups)+len(r.Scenarios)+1)
+	for _, stats := range r.Executors {
+		rows = append(rows, row(stats.Name, stats))
+	}
+	for _, stats := range r.Groups {
+		rows = append(rows, row("group "+stats.Name, stats))
+	}
+	for _, stats := range r.Scenarios {
+		rows = append(rows, row("scenario "+stats.Name, stats))
+	}
+	rows = append(rows, row("total", r.Total))
+
+	return header, rows
+}
+
+// WriteJSON writes the report as JSON, which can be read again with ReadReport.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteJSONFile writes the report as JSON to the file at path.
+func (r *Report) WriteJSONFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(r.WriteJSON(file), file.Close())
+}
+
+// ReadReport reads a report written with WriteJSON.
+func ReadReport(reader io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(reader).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid report: %w", err)
+	}
+	return &report, nil
+}
+
+// ReadReportFile reads a report written with WriteJSONFile.
+func ReadReportFile(path string) (*Report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadReport(file)
+}
+
+// reportFileWriter stores the final report of a load test as JSON.
+type reportFileWriter struct {
+	path string
+}
+
+func (w reportFileWriter) Start(_ *LoadTest) error {
+	return nil
+}
+
+func (w reportFileWriter) Finish(_ *LoadTest, report *Report) error {
+	if err := report.WriteJSONFile(w.path); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	return nil
+}
+
+// report creates a report of all results aggregated so far. The expected rates are derived
+// from the hits expected by the pacer and the share of hits each executor and group should receive.
+func (ra *resultAggregator) report(startedAt time.Time, duration time.Duration, expectedHits float64, shares *executorShares) *Report {
+	expectedRate := 0.0
+	if duration > 0 {
+		expectedRate = expectedHits / duration.Seconds()
+	}
+
+	report := &Report{
+		StartedAt: startedAt,
+		Duration:  duration,
+		Total:     newStats("total", ra.total.Load(), ra.failures.Load(), ra.latencies, ra.corrected, duration),
+	}
+	report.Total.ExpectedRate = expectedRate
+	if shares != nil {
+		report.Total.ExpectedRate = expectedRate * shares.requests
+	}
+
+	for _, name := range ra.names(ra.executors) {
+		aggregate := ra.aggregate(ra.executors, name)
+		stats := newStats(name, aggregate.total.Load(), aggregate.failures.Load(), aggregate.latencies, aggregate.corrected, duration)
+		if shares != nil {
+			stats.ExpectedRate = expectedRate * shares.executors[name]
+		}
+		report.Executors = append(report.Executors, stats)
+	}
+
+	for _, name := range ra.names(ra.groups) {
+		aggregate := ra.aggregate(ra.groups, name)
+		stats := newStats(name, aggregate.total.Load(), aggregate.failures.Load(), aggregate.latencies, aggregate.corrected, duration)
+		if shares != nil {
+			stats.ExpectedRate = expectedRate * shares.groups[name]
+		}
+		report.Groups = append(report.Groups, stats)
+	}
+
+	for _, name := range ra.names(ra.scenarios) {
+		aggregate := ra.aggregate(ra.scenarios, name)
+		stats := newStats(name, aggregate.total.Load(), aggregate.failures.Load(), aggregate.latencies, aggregate.corrected, duration)
+		if shares != nil {
+			stats.ExpectedRate = expectedRate * shares.scenarios[name]
+		}
+		report.Scenarios = append(report.Scenarios, stats)
+	}
+
+	return report
+}
+
+func newStats(name string, total int64, failures int64, latencies *histogram.Histogram, corrected *histogram.Histogram, duration time.Duration) Stats {
+	stats := Stats{
+		Name:             name,
+		Requests:         total,
+		Successes:        total - failures,
+		Failures:         failures,
+		Latency:          newLatencyDistribution(latencies),
+		CorrectedLatency: newLatencyDistribution(corrected),
+	}
+	if total > 0 {
+		stats.ErrorRate = float64(failures) / float64(total)
+	}
+	if duration > 0 {
+		stats.Rate = float64(total) / duration.Seconds()
+	}
+	return stats
+}
+
+func newLatencyDistribution(h *histogram.Histogram) LatencyDistribution {
+	snapshot := h.Snapshot()
+
+	distribution := LatencyDistribution{
+		Min:         snapshot.Min(),
+		Mean:        snapshot.Mean(),
+		Max:         snapshot.Max(),
+		Percentiles: make([]Percentile, 0, len(reportedPercentiles)),
+	}
+	for _, p := range reportedPercentiles {
+		distribution.Percentiles = append(distribution.Percentiles, Percentile{
+			Percentile: p,
+			Value:      snapshot.Percentile(p),
+		})
+	}
+
+	return distribution
+}
+
+// executorShares contains the expected share (0-1) of all hits per executor identifier, group and scenario.
+type executorShares struct {
+	executors map[string]float64
+	groups    map[string]float64
+	scenarios map[string]float64
+	// requests is the expected number of requests per hit, which is higher than 1 if scenarios are used
+	requests float64
+}
+
+func newExecutorShares(exs []Executor, weight func(ex Executor) int) *executorShares {
+	shares := &executorShares{
+		executors: map[string]float64{},
+		groups:    map[string]float64{},
+		scenarios: map[string]float64{},
+	}
+	shares.add(exs, weight, 1)
+
+	return shares
+}
+
+func (s *executorShares) add(exs []Executor, weight func(ex Executor) int, share float64) {
+	weightSum := 0
+	for _, ex := range exs {
+		weightSum += weight(ex)
+	}
+	if weightSum == 0 {
+		return
+	}
+
+	for _, ex := range exs {
+		s.addExecutor(ex, share*float64(weight(ex))/float64(weightSum))
+	}
+}
+
+// addExecutor adds an executor which is picked for the given share of all hits.
+func (s *executorShares) addExecutor(ex Executor, share float64) {
+	switch e := ex.(type) {
+	case *executorGroup:
+		s.groups[e.Name()] += share
+		s.add(e.executors, func(ex Executor) int { return ex.Options().Weight }, share)
+	case *scenario:
+		// every step is executed once per journey
+		s.scenarios[e.Name()] += share
+		for _, step := range e.steps {
+			s.addExecutor(step, share)
+		}
+	default:
+		s.executors[ex.Name()] += share
+		s.requests += share
+	}
+}
+
+func formatLatencies(stats Stats) string {
+	parts := make([]string, 0, len(reportedPercentiles)+1)
+	for _, p := range reportedPercentiles {
+		parts = append(parts, fmt.Sprintf("p%s=%s", formatPercentile(p), roundLatency(stats.Latency.Percentile(p))))
+	}
+	parts = append(parts, fmt.Sprintf("max=%s", roundLatency(stats.Latency.Max)))
+
+	return strings.Join(parts, " ")
+}
+
+func formatPercentile(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
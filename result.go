@@ -2,34 +2,156 @@ package goload
 
 import (
 	"github.com/paulbellamy/ratecounter"
+	"github.com/scayle/goload/utils/histogram"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 type Result struct {
-	Identifier     string
-	Timestamp      time.Time
+	Identifier string
+	// Group is the name of the group the executor was picked from, empty if it wasn't part of a group.
+	// For nested groups, it is the outermost one.
+	Group string
+	// Groups contains the names of all nested groups the executor was picked from, the outermost first.
+	Groups []string
+	// Scenario is the name of the scenario the result belongs to, empty if it wasn't part of a scenario.
+	Scenario string
+	// Journey is true for the result of a whole scenario run. Its steps are reported as separate results.
+	Journey bool
+	// User is the number of the virtual user which sent the hit starting at 1, zero if virtual users aren't used.
+	User      int
+	Timestamp time.Time
+	// Intended is the time the pacer scheduled the hit for. It is earlier than Timestamp if the hit was
+	// delayed, e.g. because all workers were busy.
+	Intended time.Time
+	// Latency is the service time from sending the hit until it has completed.
 	Latency        time.Duration
 	Err            error
 	AdditionalData any
 }
 
+// CorrectedLatency returns the response time from the intended send time until the hit has completed,
+// which includes the time the hit was delayed by the load generator itself.
+func (r *Result) CorrectedLatency() time.Duration {
+	if r.Intended.IsZero() || r.Intended.After(r.Timestamp) {
+		return r.Latency
+	}
+	return r.Timestamp.Sub(r.Intended) + r.Latency
+}
+
 type resultAggregator struct {
 	rateCounter *ratecounter.RateCounter
 	total       atomic.Int64
 	failures    atomic.Int64
+	latencies   *histogram.Histogram
+	corrected   *histogram.Histogram
+
+	// window contains the results since the last timeline sample
+	window *executorAggregate
+
+	mu        sync.RWMutex
+	executors map[string]*executorAggregate
+	groups    map[string]*executorAggregate
+	scenarios map[string]*executorAggregate
+}
+
+type executorAggregate struct {
+	total     atomic.Int64
+	failures  atomic.Int64
+	latencies *histogram.Histogram
+	corrected *histogram.Histogram
+}
+
+func newExecutorAggregate() *executorAggregate {
+	return &executorAggregate{
+		latencies: histogram.New(),
+		corrected: histogram.New(),
+	}
 }
 
 func newResultAggregator() *resultAggregator {
 	return &resultAggregator{
 		rateCounter: ratecounter.NewRateCounter(10 * time.Second),
+		latencies:   histogram.New(),
+		corrected:   histogram.New(),
+		window:      newExecutorAggregate(),
+		executors:   map[string]*executorAggregate{},
+		groups:      map[string]*executorAggregate{},
+		scenarios:   map[string]*executorAggregate{},
 	}
 }
 
 func (ra *resultAggregator) resultAggregationHandler(_ *LoadTest, result *Result) {
+	if result.Journey {
+		// the requests of a journey are counted by its steps
+		ra.aggregate(ra.scenarios, result.Scenario).record(result)
+		return
+	}
+
 	ra.rateCounter.Incr(1)
 	ra.total.Add(1)
+	ra.latencies.Record(result.Latency)
+	ra.corrected.Record(result.CorrectedLatency())
 	if result.Err != nil {
 		ra.failures.Add(1)
 	}
+
+	ra.window.record(result)
+	ra.aggregate(ra.executors, result.Identifier).record(result)
+	for _, group := range result.groups() {
+		ra.aggregate(ra.groups, group).record(result)
+	}
+}
+
+// groups returns the names of all groups of the result, also if only Group is set.
+func (r *Result) groups() []string {
+	if len(r.Groups) == 0 && r.Group != "" {
+		return []string{r.Group}
+	}
+	return r.Groups
+}
+
+func (ra *resultAggregator) aggregate(aggregates map[string]*executorAggregate, name string) *executorAggregate {
+	ra.mu.RLock()
+	aggregate, ok := aggregates[name]
+	ra.mu.RUnlock()
+	if ok {
+		return aggregate
+	}
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if aggregate, ok := aggregates[name]; ok {
+		return aggregate
+	}
+	aggregate = newExecutorAggregate()
+	aggregates[name] = aggregate
+
+	return aggregate
+}
+
+// names returns the names of all aggregates in alphabetical order.
+func (ra *resultAggregator) names(aggregates map[string]*executorAggregate) []string {
+	ra.mu.RLock()
+	defer ra.mu.RUnlock()
+
+	names := make([]string, 0, len(aggregates))
+	for name := range aggregates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (ea *executorAggregate) record(result *Result) {
+	ea.total.Add(1)
+	ea.latencies.Record(result.Latency)
+	ea.corrected.Record(result.CorrectedLatency())
+	if result.Err != nil {
+		ea.failures.Add(1)
+	}
 }
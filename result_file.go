@@ -0,0 +1,233 @@
+package goload
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type ResultFormat string
+
+const (
+	// ResultFormatJSONL writes one JSON object per result and line.
+	ResultFormatJSONL ResultFormat = "jsonl"
+	// ResultFormatCSV writes one row per result with a header row.
+	ResultFormatCSV ResultFormat = "csv"
+)
+
+var csvHeader = []string{"identifier", "group", "timestamp", "latency_ns", "error", "additional_data", "delay_ns", "scenario", "journey", "groups"}
+
+// resultRecord is the representation of a Result in a results file.
+type resultRecord struct {
+	Identifier string `json:"identifier"`
+	Group      string `json:"group,omitempty"`
+	// Groups is only written for nested groups.
+	Groups    []string  `json:"groups,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	LatencyNs int64     `json:"latency_ns"`
+	// DelayNs is the time the hit was sent after its intended send time.
+	DelayNs        int64  `json:"delay_ns,omitempty"`
+	Scenario       string `json:"scenario,omitempty"`
+	Journey        bool   `json:"journey,omitempty"`
+	Error          string `json:"error,omitempty"`
+	AdditionalData any    `json:"additional_data,omitempty"`
+}
+
+func newResultRecord(result *Result) resultRecord {
+	record := resultRecord{
+		Identifier:     result.Identifier,
+		Group:          result.Group,
+		Timestamp:      result.Timestamp,
+		LatencyNs:      int64(result.Latency),
+		DelayNs:        int64(result.CorrectedLatency() - result.Latency),
+		Scenario:       result.Scenario,
+		Journey:        result.Journey,
+		AdditionalData: result.AdditionalData,
+	}
+	if len(result.Groups) > 1 {
+		record.Groups = result.Groups
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+		if record.Error == "" {
+			// an empty error would be read back as a success
+			record.Error = "unknown error"
+		}
+	}
+	return record
+}
+
+// resultFileWriter streams all results to a file. Results are queued by the result handler
+// and written by a separate goroutine, so slow disks don't throttle the load test.
+type resultFileWriter struct {
+	path   string
+	format ResultFormat
+
+	file *os.File
+	done chan struct{}
+
+	encode func(record resultRecord) error
+	flush  func() error
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []resultRecord
+	closed bool
+	err    error
+}
+
+// WithResultsFile writes every result to the file at path in the given format.
+func WithResultsFile(path string, format ResultFormat) LoadTestOption {
+	writer := &resultFileWriter{
+		path:   path,
+		format: format,
+	}
+	writer.cond = sync.NewCond(&writer.mu)
+
+	return func(options *LoadTestOptions) {
+		switch format {
+		case ResultFormatJSONL, ResultFormatCSV:
+		default:
+			options.errs = append(options.errs, fmt.Errorf("unsupported result format %q", format))
+			return
+		}
+
+		options.resultHandlers = append(options.resultHandlers, writer.handleResult)
+		options.hooks = append(options.hooks, writer)
+	}
+}
+
+func (w *resultFileWriter) Start(_ *LoadTest) error {
+	file, err := os.Create(w.path)
+	if err != nil {
+		return fmt.Errorf("can't create results file: %w", err)
+	}
+	w.file = file
+	w.done = make(chan struct{})
+
+	buffered := bufio.NewWriterSize(file, 64*1024)
+	switch w.format {
+	case ResultFormatCSV:
+		csvWriter := csv.NewWriter(buffered)
+		w.encode = func(record resultRecord) error {
+			return csvWriter.Write(encodeCSVRecord(record))
+		}
+		w.flush = func() error {
+			csvWriter.Flush()
+			return errors.Join(csvWriter.Error(), buffered.Flush())
+		}
+		w.setErr(csvWriter.Write(csvHeader))
+	default:
+		encoder := json.NewEncoder(buffered)
+		w.encode = func(record resultRecord) error {
+			return encoder.Encode(record)
+		}
+		w.flush = buffered.Flush
+	}
+
+	go w.write()
+
+	return nil
+}
+
+func (w *resultFileWriter) handleResult(_ *LoadTest, result *Result) {
+	record := newResultRecord(result)
+
+	w.mu.Lock()
+	w.queue = append(w.queue, record)
+	w.mu.Unlock()
+	w.cond.Signal()
+}
+
+func (w *resultFileWriter) write() {
+	defer close(w.done)
+
+	var batch []resultRecord
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		batch, w.queue = w.queue, batch[:0]
+		closed := w.closed
+		w.mu.Unlock()
+
+		for _, record := range batch {
+			w.setErr(w.encode(record))
+		}
+
+		if closed && len(batch) == 0 {
+			w.setErr(w.flush())
+			return
+		}
+	}
+}
+
+func (w *resultFileWriter) setErr(err error) {
+	if err == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *resultFileWriter) Finish(_ *LoadTest, _ *Report) error {
+	if w.file == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.cond.Signal()
+
+	<-w.done
+
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+
+	if err != nil {
+		err = fmt.Errorf("failed to write results file: %w", err)
+	}
+	return errors.Join(err, w.file.Close())
+}
+
+func encodeCSVRecord(record resultRecord) []string {
+	additionalData := ""
+	if record.AdditionalData != nil {
+		if data, err := json.Marshal(record.AdditionalData); err == nil {
+			additionalData = string(data)
+		}
+	}
+	groups := ""
+	if len(record.Groups) > 0 {
+		if data, err := json.Marshal(record.Groups); err == nil {
+			groups = string(data)
+		}
+	}
+
+	return []string{
+		record.Identifier,
+		record.Group,
+		record.Timestamp.Format(time.RFC3339Nano),
+		strconv.FormatInt(record.LatencyNs, 10),
+		record.Error,
+		additionalData,
+		strconv.FormatInt(record.DelayNs, 10),
+		record.Scenario,
+		strconv.FormatBool(record.Journey),
+		groups,
+	}
+}
@@ -0,0 +1,114 @@
+package goload
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testResults contains a result for each kind of field of a results file.
+func testResults() []*Result {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return []*Result{
+		{Identifier: "get", Timestamp: start, Intended: start, Latency: 10 * time.Millisecond},
+		{Identifier: "post", Group: "write", Groups: []string{"write"}, Timestamp: start.Add(time.Second), Intended: start.Add(900 * time.Millisecond), Latency: 20 * time.Millisecond, Err: errors.New("status 500, body: \"oops\"")},
+		{Identifier: "nested", Group: "outer", Groups: []string{"outer", "inner"}, Timestamp: start.Add(2 * time.Second), Latency: time.Millisecond, AdditionalData: map[string]any{"status": 201.0}},
+		{Identifier: "checkout", Scenario: "checkout", Journey: true, Timestamp: start.Add(3 * time.Second), Latency: 50 * time.Millisecond, Err: errors.New("")},
+	}
+}
+
+// writeResultsFile writes the results with WithResultsFile and returns the path of the file.
+func writeResultsFile(t *testing.T, format ResultFormat, results []*Result) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "results."+string(format))
+	options := &LoadTestOptions{}
+	WithResultsFile(path, format)(options)
+	if len(options.errs) > 0 {
+		t.Fatal(errors.Join(options.errs...))
+	}
+
+	writer := options.hooks[0]
+	if err := writer.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		options.resultHandlers[0](nil, result)
+	}
+	if err := writer.Finish(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResultsFileJSONL(t *testing.T) {
+	path := writeResultsFile(t, ResultFormatJSONL, testResults())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("the file contains %d lines, expected 4", len(lines))
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"identifier": "post",
+		"group":      "write",
+		"timestamp":  "2024-01-01T12:00:01Z",
+		"latency_ns": 2e7,
+		"delay_ns":   1e8,
+		"error":      "status 500, body: \"oops\"",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+	// a single group is already contained in the group
+	if _, ok := record["groups"]; ok {
+		t.Errorf("groups = %v, expected it to be omitted", record["groups"])
+	}
+}
+
+func TestResultsFileCSV(t *testing.T) {
+	path := writeResultsFile(t, ResultFormatCSV, testResults())
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("the file contains %d rows, expected 5", len(rows))
+	}
+	if !slices.Equal(rows[0], csvHeader) {
+		t.Errorf("header = %v, want %v", rows[0], csvHeader)
+	}
+	want := []string{"nested", "outer", "2024-01-01T12:00:02Z", "1000000", "", `{"status":201}`, "0", "", "false", `["outer","inner"]`}
+	if !slices.Equal(rows[3], want) {
+		t.Errorf("row = %v, want %v", rows[3], want)
+	}
+}
+
+func TestResultsFileFormat(t *testing.T) {
+	options := &LoadTestOptions{}
+	WithResultsFile("results.xml", "xml")(options)
+	if len(options.errs) != 1 {
+		t.Errorf("expected an error for an unsupported format, got %v", options.errs)
+	}
+}
@@ -2,13 +2,20 @@ package goload
 
 import (
 	"context"
+	"fmt"
 	"github.com/mroth/weightedrand/v2"
-	"github.com/rs/zerolog/log"
 	"github.com/scayle/goload/pacer"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// maxPaceWait is the longest time the runner sleeps before it asks the pacer again for the next hit.
+const maxPaceWait = 100 * time.Millisecond
+
+// defaultWorkerIdleTimeout is the time after which an idle worker is stopped unless WithWorkerIdleTimeout is used.
+const defaultWorkerIdleTimeout = 30 * time.Second
+
 type Runner struct {
 	stopch          chan struct{}
 	stopOnce        sync.Once
@@ -19,25 +26,48 @@ type Runner struct {
 	ctxModifier    func(ctx context.Context) context.Context
 	defaultTimeout time.Duration
 
-	startedAt *time.Time
+	idleTimeout time.Duration
+
+	// startedAt is read by hooks like the control server while the runner starts
+	startedAt   atomic.Pointer[time.Time]
+	liveWorkers atomic.Int64
+	busyWorkers atomic.Int64
+
+	lateTickThreshold time.Duration
+	tickPolicy        TickPolicy
+	lateTicks         atomic.Int64
+	droppedTicks      atomic.Int64
+
+	userStages []UserStage
+	thinkTime  ThinkTime
+	usersMu    sync.Mutex
+	// users only contains the running virtual users, the stopped ones are folded into stoppedUsers
+	users        map[int]*virtualUser
+	stoppedUsers userTotals
+	startedUsers int
+	peakUsers    atomic.Int64
 }
 
 func NewRunner(loadTestOptions LoadTestOptions) *Runner {
 	a := &Runner{
-		stopch:          make(chan struct{}),
-		stopOnce:        sync.Once{},
-		workers:         loadTestOptions.initialWorkers,
-		maxWorkers:      loadTestOptions.maxWorkers,
-		weightOverrides: loadTestOptions.weightOverrides,
-		ctxModifier:     loadTestOptions.ctxModifier,
-		defaultTimeout:  loadTestOptions.defaultTimeout,
-		startedAt:       nil,
+		stopch:            make(chan struct{}),
+		stopOnce:          sync.Once{},
+		workers:           loadTestOptions.initialWorkers,
+		maxWorkers:        loadTestOptions.maxWorkers,
+		weightOverrides:   loadTestOptions.weightOverrides,
+		ctxModifier:       loadTestOptions.ctxModifier,
+		defaultTimeout:    loadTestOptions.defaultTimeout,
+		idleTimeout:       loadTestOptions.workerIdleTimeout,
+		lateTickThreshold: loadTestOptions.lateTickThreshold,
+		tickPolicy:        loadTestOptions.tickPolicy,
+		userStages:        loadTestOptions.userStages,
+		thinkTime:         loadTestOptions.thinkTime,
 	}
 
 	return a
 }
 
-func (r *Runner) Run(ctx context.Context, exs []Executor, p pacer.Pacer, du time.Duration) <-chan *Result {
+func (r *Runner) Run(ctx context.Context, exs []Executor, p pacer.Pacer, du time.Duration) (<-chan *Result, error) {
 	var wg sync.WaitGroup
 
 	workers := r.workers
@@ -45,23 +75,38 @@ func (r *Runner) Run(ctx context.Context, exs []Executor, p pacer.Pacer, du time
 		workers = r.maxWorkers
 	}
 
-	chooser := r.getExecutorChooser(exs)
+	chooser, err := r.getExecutorChooser(exs)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
-	r.startedAt = &now
-
-	results := make(chan *Result)
-	ticks := make(chan struct{})
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go r.run(chooser, now, &wg, ticks, results)
-	}
+	r.startedAt.Store(&now)
 
 	go func() {
 		<-ctx.Done()
 		r.Stop()
 	}()
 
+	results := make(chan *Result)
+	if len(r.userStages) > 0 {
+		// closed model, the pacer isn't used
+		go r.runUsers(chooser, now, du, results)
+		return results, nil
+	}
+
+	// ticks contains the intended send time of each hit
+	ticks := make(chan time.Time)
+	startWorker := func() {
+		// counted before the worker runs, so no more than maxWorkers are started
+		r.liveWorkers.Add(1)
+		wg.Add(1)
+		go r.run(chooser, now, &wg, ticks, results)
+	}
+	for i := 0; i < workers; i++ {
+		startWorker()
+	}
+
 	go func() {
 		defer func() {
 			close(ticks)
@@ -71,57 +116,127 @@ func (r *Runner) Run(ctx context.Context, exs []Executor, p pacer.Pacer, du time
 		}()
 
 		count := uint64(0)
+		// due is the offset at which the next hit is scheduled, known if the pacer was waited for
+		due := time.Duration(0)
+		dueKnown := true
 		for {
 			elapsed := time.Since(now)
-			if du > 0 && elapsed > du {
+			if du > 0 && elapsed >= du {
+				return
+			}
+			// the execution ends before its duration, or at all without one, once no further hits are expected
+			if pacer.Finished(p, count) {
 				return
 			}
 
-			wait := p.Pace(elapsed, count)
+			if wait := p.Pace(elapsed, count); wait > 0 {
+				due, dueKnown = elapsed+wait, true
+				// Sleep at most maxPaceWait before asking the pacer again, so changes of
+				// adjustable pacers are picked up quickly.
+				wait = min(wait, maxPaceWait)
+				if du > 0 {
+					wait = min(wait, du-elapsed)
+				}
+				if !r.sleep(wait) {
+					return
+				}
+				continue
+			}
 
-			time.Sleep(wait)
+			// The hit is sent late if the previous ones were blocked by busy workers. The intended
+			// send time is taken from the schedule of the pacer then, to not hide the delay.
+			if !dueKnown {
+				due = pacer.NextHitAt(p, count, due)
+			}
+			due, dueKnown = min(due, elapsed), false
+			intended := now.Add(due)
 
-			if workers < r.maxWorkers {
+			// Below the max worker count, a hit is only late because of the pacing or the scheduler,
+			// so it isn't counted as late.
+			if r.Workers() < r.maxWorkers {
 				select {
-				case ticks <- struct{}{}:
+				case ticks <- intended:
 					count++
 					continue
 				case <-r.stopch:
 					return
 				default:
-					// all workers are blocked. start one more and try again
-					workers++
-					wg.Add(1)
-					go r.run(chooser, now, &wg, ticks, results)
+					// all workers are blocked. start one more, which takes the hit
+					startWorker()
 				}
+
+				select {
+				case ticks <- intended:
+					count++
+					continue
+				case <-r.stopch:
+					return
+				}
+			}
+
+			if r.tickPolicy == TickPolicyDrop {
+				// all workers are busy, wait for one only until the hit is late
+				sent, stopped := r.sendUntil(ticks, intended, intended.Add(r.lateTickThreshold))
+				if stopped {
+					return
+				}
+				if sent {
+					r.recordTick(intended)
+				} else {
+					r.droppedTicks.Add(1)
+				}
+				count++
+				continue
 			}
 
 			select {
-			case ticks <- struct{}{}:
+			case ticks <- intended:
 				count++
+				r.recordTick(intended)
 			case <-r.stopch:
 				return
 			}
 		}
 	}()
 
-	return results
+	return results, nil
+}
+
+// sleep waits for the given duration. It returns false if the execution has been stopped in the meantime.
+func (r *Runner) sleep(wait time.Duration) bool {
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.stopch:
+		return false
+	}
 }
 
-func (r *Runner) getExecutorChooser(exs []Executor) *weightedrand.Chooser[Executor, int] {
+func (r *Runner) getExecutorChooser(exs []Executor) (*weightedrand.Chooser[Executor, int], error) {
 	choices := make([]weightedrand.Choice[Executor, int], 0, len(exs))
 	for _, ex := range exs {
-		weight := ex.Options().Weight
-		if override, ok := r.weightOverrides[ex.Name()]; ok {
-			weight = override
-		}
-		choices = append(choices, weightedrand.NewChoice(ex, weight))
+		choices = append(choices, weightedrand.NewChoice(ex, r.weight(ex)))
 	}
 	chooser, err := weightedrand.NewChooser(choices...)
 	if err != nil {
-		log.Fatal().Err(err).Msg("can't create chooser")
+		return nil, fmt.Errorf("can't create chooser: %w", err)
 	}
-	return chooser
+	return chooser, nil
+}
+
+// weight returns the weight of the executor including a possible override.
+func (r *Runner) weight(ex Executor) int {
+	if override, ok := r.weightOverrides[ex.Name()]; ok {
+		return override
+	}
+	return ex.Options().Weight
 }
 
 // Stop stops the current execution. The return value indicates whether this call
@@ -138,22 +253,101 @@ func (r *Runner) Stop() bool {
 	}
 }
 
-func (r *Runner) run(chooser *weightedrand.Chooser[Executor, int], began time.Time, workers *sync.WaitGroup, ticks <-chan struct{}, results chan<- *Result) {
+// Workers returns the number of currently running workers or virtual users.
+func (r *Runner) Workers() int {
+	return int(r.liveWorkers.Load())
+}
+
+// WorkerStats contains the number of running workers or virtual users.
+type WorkerStats struct {
+	Live int `json:"live"`
+	// Busy is the number of workers which are executing a hit at the moment.
+	Busy int `json:"busy"`
+	Idle int `json:"idle"`
+}
+
+// WorkerStats returns the number of live, busy and idle workers or virtual users.
+func (r *Runner) WorkerStats() WorkerStats {
+	live := r.Workers()
+	busy := min(int(r.busyWorkers.Load()), live)
+	return WorkerStats{
+		Live: live,
+		Busy: busy,
+		Idle: live - busy,
+	}
+}
+
+// run sends the hits it receives until ticks is closed or it was idle for longer than the idle timeout.
+// The worker has to be counted in liveWorkers before.
+func (r *Runner) run(chooser *weightedrand.Chooser[Executor, int], began time.Time, workers *sync.WaitGroup, ticks <-chan time.Time, results chan<- *Result) {
 	defer workers.Done()
 
-	for range ticks {
-		results <- r.hit(chooser.Pick(), began)
+	// idle is nil if idle workers are never stopped
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if r.idleTimeout > 0 {
+		timer = time.NewTimer(r.idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	for {
+		select {
+		case intended, ok := <-ticks:
+			if !ok {
+				r.liveWorkers.Add(-1)
+				return
+			}
+			for _, result := range r.hit(chooser.Pick(), began, intended, nil) {
+				results <- result
+			}
+			if timer != nil {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(r.idleTimeout)
+			}
+		case <-idle:
+			if r.retireWorker() {
+				return
+			}
+			timer.Reset(r.idleTimeout)
+		}
 	}
 }
 
-func (r *Runner) hit(ex Executor, began time.Time) *Result {
+// retireWorker removes an idle worker from liveWorkers unless only the initial workers are left.
+// It returns true if the worker has to stop.
+func (r *Runner) retireWorker() bool {
+	minWorkers := int64(min(r.workers, r.maxWorkers))
+	for {
+		live := r.liveWorkers.Load()
+		if live <= minWorkers {
+			return false
+		}
+		if r.liveWorkers.CompareAndSwap(live, live-1) {
+			return true
+		}
+	}
+}
+
+// hit executes the executor once. intended is the time the hit was scheduled for, a zero time means now.
+// userVars are the variables of the virtual user, nil if virtual users aren't used.
+// The result of the hit is the last one, it is preceded by the results of the steps if a scenario was executed.
+func (r *Runner) hit(ex Executor, began time.Time, intended time.Time, userVars *Variables) []*Result {
 	res := Result{
 		Timestamp: began.Add(time.Since(began)),
+		Intended:  intended,
+	}
+	if intended.IsZero() {
+		res.Intended = res.Timestamp
 	}
 
-	defer func() {
-		res.Latency = time.Since(res.Timestamp)
-	}()
+	r.busyWorkers.Add(1)
+	defer r.busyWorkers.Add(-1)
 
 	ctx := context.Background()
 	if ex.Options().Timeout > 0 {
@@ -166,15 +360,45 @@ func (r *Runner) hit(ex Executor, began time.Time) *Result {
 		defer cancel()
 	}
 
+	ctx = ContextWithVariables(ctx, newVariables(userVars))
 	if r.ctxModifier != nil {
 		ctx = r.ctxModifier(ctx)
 	}
 
 	resp := ex.Execute(ctx)
+	res.Latency = time.Since(res.Timestamp)
 
+	if len(resp.Groups) > 0 {
+		res.Group = resp.Groups[0]
+		res.Groups = resp.Groups
+	}
 	res.Identifier = resp.Identifier
 	res.AdditionalData = resp.AdditionalData
 	res.Err = resp.Err
+	if resp.Steps == nil {
+		return []*Result{&res}
+	}
 
-	return &res
+	res.Scenario = resp.Identifier
+	res.Journey = true
+	results := make([]*Result, 0, len(resp.Steps)+1)
+	for i, step := range resp.Steps {
+		stepResult := &Result{
+			Identifier:     step.Identifier,
+			Group:          step.Group,
+			Groups:         step.Groups,
+			Scenario:       res.Scenario,
+			Timestamp:      step.Timestamp,
+			Intended:       step.Timestamp,
+			Latency:        step.Latency,
+			Err:            step.Err,
+			AdditionalData: step.AdditionalData,
+		}
+		if i == 0 {
+			// the first step was delayed together with the journey
+			stepResult.Intended = res.Intended
+		}
+		results = append(results, stepResult)
+	}
+	return append(results, &res)
 }
@@ -0,0 +1,79 @@
+package goload
+
+import (
+	"context"
+	"github.com/scayle/goload/pacer"
+	"testing"
+	"time"
+)
+
+func TestRunnerEndsWhenPacerFinished(t *testing.T) {
+	steps := []pacer.Step{{Rate: pacer.Rate{Freq: 20, Per: time.Second}, Hold: 500 * time.Millisecond}, {Rate: pacer.Rate{Freq: 0, Per: time.Second}}}
+	lt, err := New(
+		WithPacer(pacer.NewStepPacer(steps, 0)),
+		WithExecutors(NewGenericExecutor("noop", func(ctx context.Context) error { return nil })),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the load test has no duration, so it only ends because the pacer is finished
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := lt.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("the load test didn't end after the last hit")
+	}
+	if report.Total.Requests != 10 {
+		t.Errorf("%d hits were sent, expected 10", report.Total.Requests)
+	}
+}
+
+func TestRunnerReapsIdleWorkers(t *testing.T) {
+	r := NewRunner(LoadTestOptions{
+		initialWorkers:    1,
+		maxWorkers:        100,
+		workerIdleTimeout: 100 * time.Millisecond,
+		tickPolicy:        TickPolicyDelay,
+	})
+	slow := NewGenericExecutor("slow", func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	// a spike which needs about ten workers, followed by no hits until the end of the run
+	steps := []pacer.Step{{Rate: pacer.Rate{Freq: 200, Per: time.Second}, Hold: 200 * time.Millisecond}, {Rate: pacer.Rate{Freq: 1, Per: time.Second}}}
+
+	results, err := r.Run(context.Background(), []Executor{slow}, pacer.NewStepPacer(steps, 0), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range results {
+		}
+	}()
+
+	peak := 0
+	for i := 0; i < 25; i++ {
+		time.Sleep(10 * time.Millisecond)
+		peak = max(peak, r.Workers())
+	}
+	if peak <= 1 {
+		t.Fatalf("expected more workers during the spike, got %d", peak)
+	}
+
+	// the spike ends at 250ms, the extra workers are stopped after being idle for 100ms
+	time.Sleep(450 * time.Millisecond)
+	if workers := r.Workers(); workers != 1 {
+		t.Errorf("%d workers are left after the spike, expected only the initial one", workers)
+	}
+
+	<-done
+	if workers := r.Workers(); workers != 0 {
+		t.Errorf("%d workers are left after the run", workers)
+	}
+}
@@ -0,0 +1,108 @@
+package goload
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultLateTickThreshold is the delay after which a hit counts as late unless WithLateTickThreshold is used.
+const defaultLateTickThreshold = 10 * time.Millisecond
+
+// TickPolicy decides what happens to a hit if all workers are busy and no more workers can be started.
+type TickPolicy string
+
+const (
+	// TickPolicyDelay sends the hit as soon as a worker is free, no matter how late it is.
+	TickPolicyDelay TickPolicy = "delay"
+	// TickPolicyDrop drops the hit if no worker is free before it is late by more than the threshold.
+	TickPolicyDrop TickPolicy = "drop"
+)
+
+// SaturationStats counts the hits the load generator couldn't send on time because all workers were busy.
+// If any hit was late or dropped, the achieved rate is limited by the load generator and not by the target.
+type SaturationStats struct {
+	// Threshold is the delay after which a hit counts as late.
+	Threshold time.Duration `json:"threshold_ns"`
+	Policy    TickPolicy    `json:"policy"`
+	// LateTicks is the number of hits which were sent later than the threshold after their intended send time
+	// because the max worker count was reached.
+	LateTicks int64 `json:"late_ticks"`
+	// DroppedTicks is the number of hits which weren't sent at all, see TickPolicyDrop.
+	DroppedTicks int64 `json:"dropped_ticks"`
+}
+
+// Saturated returns true if at least one hit was late or dropped.
+func (s SaturationStats) Saturated() bool {
+	return s.LateTicks > 0 || s.DroppedTicks > 0
+}
+
+// WithLateTickThreshold sets the delay after the intended send time after which a hit counts as late.
+// Defaults to 10ms.
+func WithLateTickThreshold(threshold time.Duration) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		if threshold < 0 {
+			options.errs = append(options.errs, fmt.Errorf("late tick threshold must be >= 0"))
+			return
+		}
+		options.lateTickThreshold = threshold
+	}
+}
+
+// WithTickPolicy sets what happens to hits if the max worker count is reached and all workers are busy.
+// Defaults to TickPolicyDelay.
+func WithTickPolicy(policy TickPolicy) LoadTestOption {
+	return func(options *LoadTestOptions) {
+		switch policy {
+		case TickPolicyDelay, TickPolicyDrop:
+			options.tickPolicy = policy
+		default:
+			options.errs = append(options.errs, fmt.Errorf("unsupported tick policy %q", policy))
+		}
+	}
+}
+
+// Saturation returns the hits which were late or dropped so far.
+func (r *Runner) Saturation() SaturationStats {
+	return SaturationStats{
+		Threshold:    r.lateTickThreshold,
+		Policy:       r.tickPolicy,
+		LateTicks:    r.lateTicks.Load(),
+		DroppedTicks: r.droppedTicks.Load(),
+	}
+}
+
+// recordTick counts the hit as late if it was handed to a worker later than the threshold. It is only called
+// once the max worker count has been reached.
+func (r *Runner) recordTick(intended time.Time) {
+	if time.Since(intended) > r.lateTickThreshold {
+		r.lateTicks.Add(1)
+	}
+}
+
+// sendUntil waits for a free worker to send the hit until the deadline. It returns false if
+// the hit wasn't sent and stopped is true if the execution was stopped in the meantime.
+func (r *Runner) sendUntil(ticks chan<- time.Time, intended time.Time, deadline time.Time) (sent bool, stopped bool) {
+	select {
+	case ticks <- intended:
+		return true, false
+	default:
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case ticks <- intended:
+		return true, false
+	case <-timer.C:
+		return false, false
+	case <-r.stopch:
+		return false, true
+	}
+}
+
+// formatSaturation returns a warning about the hits which couldn't be sent on time.
+func formatSaturation(s *SaturationStats) string {
+	return fmt.Sprintf("load generator saturated: %d hits late by more than %s, %d dropped. Increase the max worker count or lower the rate",
+		s.LateTicks, s.Threshold, s.DroppedTicks)
+}
@@ -0,0 +1,74 @@
+package goload
+
+import (
+	"context"
+	"github.com/scayle/goload/pacer"
+	"testing"
+	"time"
+)
+
+// runSaturated runs a load test with a single worker which can't keep up with the rate.
+func runSaturated(t *testing.T, delay time.Duration, opts ...LoadTestOption) *Report {
+	t.Helper()
+
+	opts = append([]LoadTestOption{
+		WithPacer(pacer.NewConstantPacer(pacer.Rate{Freq: 100, Per: time.Second})),
+		WithDuration(500 * time.Millisecond),
+		WithInitialWorkerCount(1),
+		WithMaxWorkerCount(1),
+		WithExecutors(NewGenericExecutor("slow", func(ctx context.Context) error {
+			time.Sleep(delay)
+			return nil
+		})),
+	}, opts...)
+	lt, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := lt.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Saturation == nil {
+		t.Fatal("expected saturation stats")
+	}
+	return report
+}
+
+func TestLateTicks(t *testing.T) {
+	report := runSaturated(t, 50*time.Millisecond)
+
+	saturation := report.Saturation
+	if saturation.Policy != TickPolicyDelay || saturation.Threshold != defaultLateTickThreshold {
+		t.Errorf("unexpected settings %+v", saturation)
+	}
+	if saturation.LateTicks == 0 || saturation.DroppedTicks != 0 {
+		t.Errorf("expected only late hits, got %+v", saturation)
+	}
+	// every hit but the first waited for the busy worker
+	if saturation.LateTicks < report.Total.Requests-1 {
+		t.Errorf("%d of %d hits were late", saturation.LateTicks, report.Total.Requests)
+	}
+}
+
+func TestDroppedTicks(t *testing.T) {
+	report := runSaturated(t, 50*time.Millisecond, WithTickPolicy(TickPolicyDrop))
+
+	saturation := report.Saturation
+	if saturation.DroppedTicks == 0 || !saturation.Saturated() {
+		t.Errorf("expected dropped hits, got %+v", saturation)
+	}
+	// the dropped hits are skipped, so the sent and dropped ones together follow the rate
+	if total := report.Total.Requests + saturation.DroppedTicks; total < 40 || total > 51 {
+		t.Errorf("%d hits were sent and %d dropped, expected about 50 in total", report.Total.Requests, saturation.DroppedTicks)
+	}
+}
+
+func TestNotSaturated(t *testing.T) {
+	report := runSaturated(t, 0, WithTickPolicy(TickPolicyDrop), WithLateTickThreshold(50*time.Millisecond))
+
+	if report.Saturation.Saturated() {
+		t.Errorf("expected no late or dropped hits, got %+v", report.Saturation)
+	}
+}
@@ -0,0 +1,289 @@
+package goload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Variables stores the values which are shared between the steps of a scenario, e.g. a token or an ID
+// taken from an earlier response. It is safe for concurrent use.
+//
+// Each hit has its own variables. If virtual users are used, they fall back to the variables of the
+// virtual user, which are kept between its iterations, see User.
+type Variables struct {
+	parent *Variables
+
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+func NewVariables() *Variables {
+	return newVariables(nil)
+}
+
+// newVariables creates variables which fall back to parent for values they don't contain.
+func newVariables(parent *Variables) *Variables {
+	return &Variables{
+		parent: parent,
+		values: map[string]any{},
+	}
+}
+
+// Get returns the value of the variable with the given name. A nil Variables has no values.
+func (v *Variables) Get(name string) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	v.mu.RLock()
+	value, ok := v.values[name]
+	v.mu.RUnlock()
+	if !ok {
+		return v.parent.Get(name)
+	}
+	return value, ok
+}
+
+// User returns the variables of the virtual user, which are kept between its iterations. Without
+// virtual users, the variables of the hit itself are returned.
+func (v *Variables) User() *Variables {
+	if v.parent != nil {
+		return v.parent
+	}
+	return v
+}
+
+// String returns the value of the variable with the given name formatted as a string, empty if it isn't set.
+func (v *Variables) String(name string) string {
+	value, ok := v.Get(name)
+	if !ok {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func (v *Variables) Set(name string, value any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.values[name] = value
+}
+
+// Expand replaces all references to variables in the form ${name} by their values. References to
+// unknown variables are kept as they are.
+func (v *Variables) Expand(s string) string {
+	if v == nil || !strings.Contains(s, "${") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(s[:start])
+		name := s[start+2 : end]
+		if _, ok := v.Get(name); ok {
+			b.WriteString(v.String(name))
+		} else {
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+
+	return b.String()
+}
+
+type variablesKey struct{}
+
+// ContextWithVariables returns a copy of ctx which carries the given variables.
+func ContextWithVariables(ctx context.Context, vars *Variables) context.Context {
+	return context.WithValue(ctx, variablesKey{}, vars)
+}
+
+// VariablesFromContext returns the variables of the current hit, nil if ctx doesn't carry any.
+func VariablesFromContext(ctx context.Context) *Variables {
+	vars, _ := ctx.Value(variablesKey{}).(*Variables)
+	return vars
+}
+
+type ScenarioOptions struct {
+	name      string
+	weight    int
+	timeout   time.Duration
+	thinkTime ThinkTime
+	steps     []Executor
+}
+
+type ScenarioOption func(*ScenarioOptions)
+
+// WithScenario creates an executor which runs its steps in order on each execution, e.g. login, browse
+// a product, add it to the basket and checkout. The steps share Variables which they can get from
+// their context with VariablesFromContext. The journey stops at the first failed step.
+//
+// Each step is reported like a single executor and the whole journey is reported as a scenario.
+func WithScenario(opts ...ScenarioOption) Executor {
+	options := &ScenarioOptions{
+		weight: 1,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.name == "" {
+		return NewInvalidExecutor(errors.New("scenario needs a name"))
+	}
+	if len(options.steps) == 0 {
+		return NewInvalidExecutor(fmt.Errorf("scenario %q needs at least one step", options.name))
+	}
+
+	return &scenario{
+		name:      options.name,
+		steps:     options.steps,
+		weight:    options.weight,
+		timeout:   options.timeout,
+		thinkTime: options.thinkTime,
+	}
+}
+
+func WithScenarioName(name string) ScenarioOption {
+	return func(options *ScenarioOptions) {
+		options.name = name
+	}
+}
+
+func WithScenarioWeight(weight int) ScenarioOption {
+	return func(options *ScenarioOptions) {
+		options.weight = weight
+	}
+}
+
+// WithScenarioTimeout limits the duration of the whole journey. The timeouts of the steps apply to each step.
+func WithScenarioTimeout(timeout time.Duration) ScenarioOption {
+	return func(options *ScenarioOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithScenarioSteps appends steps which are executed in the given order.
+func WithScenarioSteps(steps ...Executor) ScenarioOption {
+	return func(options *ScenarioOptions) {
+		options.steps = append(options.steps, steps...)
+	}
+}
+
+// WithScenarioThinkTime pauses between the steps of a journey.
+func WithScenarioThinkTime(thinkTime ThinkTime) ScenarioOption {
+	return func(options *ScenarioOptions) {
+		options.thinkTime = thinkTime
+	}
+}
+
+type scenario struct {
+	name      string
+	steps     []Executor
+	weight    int
+	timeout   time.Duration
+	thinkTime ThinkTime
+}
+
+// StepResponse is the outcome of a single step of a scenario.
+type StepResponse struct {
+	ExecutionResponse
+	// Group is the name of the group the step was picked from, empty if the step isn't a group.
+	Group     string
+	Timestamp time.Time
+	Latency   time.Duration
+}
+
+func (s *scenario) Execute(ctx context.Context) ExecutionResponse {
+	if VariablesFromContext(ctx) == nil {
+		ctx = ContextWithVariables(ctx, NewVariables())
+	}
+
+	response := ExecutionResponse{
+		Identifier: s.name,
+		Steps:      make([]StepResponse, 0, len(s.steps)),
+	}
+	for i, step := range s.steps {
+		if i > 0 && s.thinkTime != nil {
+			if err := s.think(ctx); err != nil {
+				response.Err = err
+				break
+			}
+		}
+
+		stepResponse := s.executeStep(ctx, step)
+		response.Steps = append(response.Steps, stepResponse)
+		if stepResponse.Err != nil {
+			response.Err = fmt.Errorf("step %q failed: %w", stepResponse.Identifier, stepResponse.Err)
+			break
+		}
+	}
+
+	return response
+}
+
+func (s *scenario) executeStep(ctx context.Context, step Executor) StepResponse {
+	response := StepResponse{
+		Timestamp: time.Now(),
+	}
+	if _, ok := step.(*executorGroup); ok {
+		response.Group = step.Name()
+	}
+
+	if timeout := step.Options().Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	response.ExecutionResponse = step.Execute(ctx)
+	response.Latency = time.Since(response.Timestamp)
+
+	return response
+}
+
+// think waits for the think time between two steps. It returns an error if the journey was canceled in the meantime.
+func (s *scenario) think(ctx context.Context) error {
+	think := s.thinkTime()
+	if think <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(think)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *scenario) Name() string {
+	return s.name
+}
+
+func (s *scenario) Options() *ExecutorOptions {
+	return &ExecutorOptions{
+		Weight:  s.weight,
+		Timeout: s.timeout,
+	}
+}
@@ -0,0 +1,103 @@
+package goload
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScenarioStepFlow(t *testing.T) {
+	errOutOfStock := errors.New("out of stock")
+	var executed []string
+	step := func(name string, handler func(vars *Variables) error) Executor {
+		return NewGenericExecutor(name, func(ctx context.Context) error {
+			executed = append(executed, name)
+			return handler(VariablesFromContext(ctx))
+		})
+	}
+
+	checkout := WithScenario(
+		WithScenarioName("checkout"),
+		WithScenarioSteps(
+			step("login", func(vars *Variables) error {
+				vars.Set("token", "secret")
+				return nil
+			}),
+			step("basket", func(vars *Variables) error {
+				if token := vars.Expand("Bearer ${token}"); token != "Bearer secret" {
+					t.Errorf("the variables weren't shared between the steps, got %q", token)
+				}
+				return errOutOfStock
+			}),
+			step("pay", func(vars *Variables) error { return nil }),
+		),
+	)
+
+	results := (&Runner{}).hit(checkout, time.Now(), time.Time{}, nil)
+
+	if expected := []string{"login", "basket"}; !reflect.DeepEqual(executed, expected) {
+		t.Errorf("executed steps %v, expected %v", executed, expected)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected two steps and the journey, got %d results", len(results))
+	}
+
+	login, basket, journey := results[0], results[1], results[2]
+	if login.Identifier != "login" || login.Err != nil || login.Scenario != "checkout" || login.Journey {
+		t.Errorf("unexpected result of the first step %+v", login)
+	}
+	if basket.Identifier != "basket" || !errors.Is(basket.Err, errOutOfStock) || basket.Scenario != "checkout" {
+		t.Errorf("unexpected result of the failed step %+v", basket)
+	}
+	if basket.Timestamp.Before(login.Timestamp) {
+		t.Error("the steps weren't executed in order")
+	}
+	if journey.Identifier != "checkout" || !journey.Journey || !errors.Is(journey.Err, errOutOfStock) {
+		t.Errorf("unexpected result of the journey %+v", journey)
+	}
+	if journey.Latency < login.Latency+basket.Latency {
+		t.Errorf("the journey took %s, less than its steps", journey.Latency)
+	}
+}
+
+func TestScenarioThinkTime(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+	checkout := WithScenario(
+		WithScenarioName("checkout"),
+		WithScenarioThinkTime(ConstantThinkTime(50*time.Millisecond)),
+		WithScenarioSteps(NewGenericExecutor("login", noop), NewGenericExecutor("pay", noop)),
+	)
+
+	response := checkout.Execute(context.Background())
+	if response.Err != nil || len(response.Steps) != 2 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	if gap := response.Steps[1].Timestamp.Sub(response.Steps[0].Timestamp); gap < 50*time.Millisecond {
+		t.Errorf("the steps were only %s apart", gap)
+	}
+
+	// the think time is interrupted when the journey is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	response = checkout.Execute(ctx)
+	if !errors.Is(response.Err, context.DeadlineExceeded) || len(response.Steps) != 1 {
+		t.Errorf("expected the journey to end after the first step, got %+v", response)
+	}
+}
+
+func TestInvalidScenario(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ScenarioOption
+	}{
+		{name: "without name", opts: []ScenarioOption{WithScenarioSteps(NewGenericExecutor("login", nil))}},
+		{name: "without steps", opts: []ScenarioOption{WithScenarioName("checkout")}},
+	}
+	for _, tt := range tests {
+		if err := WithScenario(tt.opts...).Execute(context.Background()).Err; err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
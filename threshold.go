@@ -0,0 +1,273 @@
+package goload
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrThresholdsBreached is returned by LoadTest.Run if at least one threshold has failed.
+var ErrThresholdsBreached = errors.New("thresholds breached")
+
+// Threshold is a pass/fail criterion which is checked against the aggregated results
+// at the end of a run and optionally while it is running.
+type Threshold struct {
+	// Description is a human-readable representation of the criterion, e.g. `p95 latency of "pi" < 300ms`.
+	Description string
+
+	abortOnFail bool
+	gracePeriod time.Duration
+
+	check func(stats Stats) (actual string, ok bool)
+	name  string
+	err   error
+}
+
+// ThresholdResult is the outcome of a single threshold.
+type ThresholdResult struct {
+	Description string `json:"description"`
+	Actual      string `json:"actual"`
+	Passed      bool   `json:"passed"`
+}
+
+// LatencyThreshold requires the latency at the given percentile to be lower than max.
+// Supported percentiles are 50, 90, 95, 99, 99.9 and 100 (the maximum latency).
+//
+// An empty name checks the latency over all executors, otherwise the executor, group or
+// scenario with the given name is checked.
+func LatencyThreshold(name string, percentile float64, max time.Duration) Threshold {
+	threshold := Threshold{
+		Description: fmt.Sprintf("p%s latency of %s < %s", formatPercentile(percentile), thresholdSubject(name), max),
+		name:        name,
+		check: func(stats Stats) (string, bool) {
+			latency := stats.Latency.Max
+			if percentile < 100 {
+				latency = stats.Latency.Percentile(percentile)
+			}
+			return roundLatency(latency).String(), latency < max
+		},
+	}
+	if percentile != 100 && !slices.Contains(reportedPercentiles, percentile) {
+		threshold.err = fmt.Errorf("unsupported percentile %s in threshold %q", formatPercentile(percentile), threshold.Description)
+	}
+	return threshold
+}
+
+// ErrorRateThreshold requires the share of failed requests (0-1) to be lower than max.
+func ErrorRateThreshold(name string, max float64) Threshold {
+	return Threshold{
+		Description: fmt.Sprintf("error rate of %s < %.2f%%", thresholdSubject(name), max*100),
+		name:        name,
+		check: func(stats Stats) (string, bool) {
+			return fmt.Sprintf("%.2f%%", stats.ErrorRate*100), stats.ErrorRate < max
+		},
+	}
+}
+
+// AchievedRateThreshold requires the achieved rate to be at least the given ratio (0-1)
+// of the rate the pacer was expected to achieve.
+func AchievedRateThreshold(name string, minRatio float64) Threshold {
+	return Threshold{
+		Description: fmt.Sprintf("achieved rate of %s >= %.2f%% of target", thresholdSubject(name), minRatio*100),
+		name:        name,
+		check: func(stats Stats) (string, bool) {
+			if stats.ExpectedRate == 0 {
+				return "no target rate", true
+			}
+			ratio := stats.Rate / stats.ExpectedRate
+			return fmt.Sprintf("%.2f%%", ratio*100), ratio >= minRatio
+		},
+	}
+}
+
+// AbortOnFail returns a copy of the threshold which is also checked while the load test
+// is running. The load test is stopped as soon as the threshold fails. The check only starts
+// after the grace period, which allows to skip e.g. the warm-up phase.
+func (t Threshold) AbortOnFail(gracePeriod time.Duration) Threshold {
+	t.abortOnFail = true
+	t.gracePeriod = gracePeriod
+	return t
+}
+
+// evaluate checks the threshold against the report. The second return value is false if
+// there are no results for the checked executor yet, in which case the threshold fails.
+func (t Threshold) evaluate(report *Report) (ThresholdResult, bool) {
+	result := ThresholdResult{
+		Description: t.Description,
+	}
+
+	stats, ok := report.stats(t.name)
+	if !ok || stats.Requests == 0 {
+		result.Actual = "no results"
+		return result, false
+	}
+
+	result.Actual, result.Passed = t.check(stats)
+	return result, true
+}
+
+// stats returns the statistics of the executor, group or scenario with the given name or
+// the total statistics if the name is empty.
+func (r *Report) stats(name string) (Stats, bool) {
+	if name == "" {
+		return r.Total, true
+	}
+	if stats, ok := r.Executor(name); ok {
+		return stats, true
+	}
+	if stats, ok := r.Group(name); ok {
+		return stats, true
+	}
+	return r.Scenario(name)
+}
+
+// FailedThresholds returns all thresholds of the report which didn't pass.
+func (r *Report) FailedThresholds() []ThresholdResult {
+	var failed []ThresholdResult
+	for _, result := range r.Thresholds {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// CheckThresholds evaluates the thresholds against the report and stores their outcome in
+// Thresholds. An error wrapping ErrThresholdsBreached is returned if at least one has failed, joined
+// with the errors of all invalid thresholds.
+func (r *Report) CheckThresholds(thresholds ...Threshold) error {
+	r.Thresholds = make([]ThresholdResult, 0, len(thresholds))
+	var errs []error
+	for _, threshold := range thresholds {
+		if threshold.err != nil {
+			errs = append(errs, threshold.err)
+			continue
+		}
+		result, _ := threshold.evaluate(r)
+		r.Thresholds = append(r.Thresholds, result)
+	}
+
+	failed := r.FailedThresholds()
+	if len(failed) > 0 {
+		descriptions := make([]string, 0, len(failed))
+		for _, result := range failed {
+			descriptions = append(descriptions, fmt.Sprintf("%s (actual: %s)", result.Description, result.Actual))
+		}
+		errs = append(errs, fmt.Errorf("%w: %s", ErrThresholdsBreached, strings.Join(descriptions, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// watchThresholds periodically checks all thresholds which should abort the load test and
+// stops the runner as soon as one of them fails.
+func (lt *LoadTest) watchThresholds() {
+	var thresholds []Threshold
+	for _, threshold := range lt.thresholds {
+		if threshold.abortOnFail {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	if len(thresholds) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lt.done:
+				return
+			case <-ticker.C:
+				report := lt.Report()
+				for _, threshold := range thresholds {
+					if report.Duration < threshold.gracePeriod {
+						continue
+					}
+					result, ok := threshold.evaluate(report)
+					if !ok || result.Passed {
+						continue
+					}
+
+					lt.abortedBy.Store(&result)
+					lt.Runner.Stop()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// ParseThreshold parses a threshold from its textual representation `[name:]metric op value`.
+// The name of an executor, group or scenario is optional and defaults to all executors. Supported are:
+//
+//	p95 < 300ms         latency percentile (p50, p90, p95, p99, p99.9) or max below a duration
+//	error_rate < 1%     error rate below a percentage or ratio
+//	rate >= 95%         achieved rate of at least a percentage or ratio of the target rate
+func ParseThreshold(s string) (Threshold, error) {
+	name := ""
+	expr := s
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		name, expr = strings.TrimSpace(s[:i]), s[i+1:]
+	}
+
+	op := "<"
+	if strings.Contains(expr, ">=") {
+		op = ">="
+	}
+	metric, value, ok := strings.Cut(expr, op)
+	if !ok {
+		return Threshold{}, fmt.Errorf("invalid threshold %q: missing operator", s)
+	}
+	metric, value = strings.TrimSpace(metric), strings.TrimSpace(value)
+
+	switch {
+	case metric == "error_rate" && op == "<":
+		ratio, err := parseRatio(value)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold %q: %w", s, err)
+		}
+		return ErrorRateThreshold(name, ratio), nil
+	case metric == "rate" && op == ">=":
+		ratio, err := parseRatio(value)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold %q: %w", s, err)
+		}
+		return AchievedRateThreshold(name, ratio), nil
+	case (metric == "max" || strings.HasPrefix(metric, "p")) && op == "<":
+		percentile := 100.0
+		if metric != "max" {
+			var err error
+			percentile, err = strconv.ParseFloat(metric[1:], 64)
+			if err != nil {
+				return Threshold{}, fmt.Errorf("invalid threshold %q: invalid percentile", s)
+			}
+		}
+		max, err := time.ParseDuration(value)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold %q: %w", s, err)
+		}
+		threshold := LatencyThreshold(name, percentile, max)
+		return threshold, threshold.err
+	default:
+		return Threshold{}, fmt.Errorf("invalid threshold %q: unsupported metric %q with operator %q", s, metric, op)
+	}
+}
+
+func parseRatio(value string) (float64, error) {
+	if percentage, ok := strings.CutSuffix(value, "%"); ok {
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(percentage), 64)
+		return ratio / 100, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func thresholdSubject(name string) string {
+	if name == "" {
+		return "all executors"
+	}
+	return fmt.Sprintf("%q", name)
+}
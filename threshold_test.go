@@ -0,0 +1,103 @@
+package goload
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		in          string
+		description string
+		wantErr     bool
+	}{
+		{in: "p95 < 300ms", description: `p95 latency of all executors < 300ms`},
+		{in: "search: p99.9<1s", description: `p99.9 latency of "search" < 1s`},
+		{in: "max < 2s", description: `p100 latency of all executors < 2s`},
+		{in: "checkout:error_rate < 1%", description: `error rate of "checkout" < 1.00%`},
+		{in: "error_rate < 0.05", description: `error rate of all executors < 5.00%`},
+		{in: "rate >= 95%", description: `achieved rate of all executors >= 95.00% of target`},
+		{in: "api:v2: rate >= 0.9", description: `achieved rate of "api:v2" >= 90.00% of target`},
+		{in: "p42 < 1s", wantErr: true},
+		{in: "px < 1s", wantErr: true},
+		{in: "p95 < fast", wantErr: true},
+		{in: "p95 >= 1s", wantErr: true},
+		{in: "error_rate < many", wantErr: true},
+		{in: "rate < 90%", wantErr: true},
+		{in: "latency", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			threshold, err := ParseThreshold(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThreshold(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && threshold.Description != tt.description {
+				t.Errorf("ParseThreshold(%q) = %q, want %q", tt.in, threshold.Description, tt.description)
+			}
+		})
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	report := &Report{
+		Total: Stats{
+			Requests:     100,
+			Failures:     2,
+			ErrorRate:    0.02,
+			Rate:         9.5,
+			ExpectedRate: 10,
+			Latency: LatencyDistribution{
+				Max:         time.Second,
+				Percentiles: []Percentile{{Percentile: 95, Value: 250 * time.Millisecond}},
+			},
+		},
+		Executors: []Stats{{Name: "search", Requests: 100}},
+	}
+
+	tests := []struct {
+		threshold string
+		actual    string
+		passed    bool
+	}{
+		{threshold: "p95 < 300ms", actual: "250ms", passed: true},
+		{threshold: "p95 < 250ms", actual: "250ms", passed: false},
+		{threshold: "max < 1s", actual: "1s", passed: false},
+		{threshold: "error_rate < 5%", actual: "2.00%", passed: true},
+		{threshold: "error_rate < 1%", actual: "2.00%", passed: false},
+		{threshold: "rate >= 95%", actual: "95.00%", passed: true},
+		{threshold: "search: error_rate < 1%", actual: "0.00%", passed: true},
+		{threshold: "checkout: p95 < 1s", actual: "no results", passed: false},
+	}
+	for _, tt := range tests {
+		threshold, err := ParseThreshold(tt.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = report.CheckThresholds(threshold)
+		if len(report.Thresholds) != 1 {
+			t.Fatalf("%s: expected one result, got %d", tt.threshold, len(report.Thresholds))
+		}
+		result := report.Thresholds[0]
+		if result.Actual != tt.actual || result.Passed != tt.passed {
+			t.Errorf("%s: actual %s, passed %t, want %s, %t", tt.threshold, result.Actual, result.Passed, tt.actual, tt.passed)
+		}
+		if errors.Is(err, ErrThresholdsBreached) == tt.passed {
+			t.Errorf("%s: unexpected error %v", tt.threshold, err)
+		}
+	}
+}
+
+func TestCheckInvalidThresholds(t *testing.T) {
+	report := &Report{Total: Stats{Requests: 1}}
+
+	err := report.CheckThresholds(LatencyThreshold("", 42, time.Second), ErrorRateThreshold("", 0.1), LatencyThreshold("", 75, time.Second))
+	if err == nil || errors.Is(err, ErrThresholdsBreached) {
+		t.Fatalf("expected only the errors of the invalid thresholds, got %v", err)
+	}
+	if len(report.Thresholds) != 1 || !report.Thresholds[0].Passed {
+		t.Errorf("expected the valid threshold to pass, got %+v", report.Thresholds)
+	}
+}
@@ -0,0 +1,84 @@
+package goload
+
+import (
+	"time"
+)
+
+// timelinePercentiles are the latency percentiles which are recorded in the timeline.
+var timelinePercentiles = []float64{50, 90, 95, 99}
+
+// TimelineSample contains the results of a single interval of a load test run.
+type TimelineSample struct {
+	// Offset is the end of the interval relative to the start of the run.
+	Offset       time.Duration `json:"offset_ns"`
+	ExpectedRate float64       `json:"expected_rate"`
+	Rate         float64       `json:"rate"`
+	Requests     int64         `json:"requests"`
+	ErrorRate    float64       `json:"error_rate"`
+	// Workers is the number of running workers or virtual users.
+	Workers int `json:"workers"`
+	// BusyWorkers is the number of workers or virtual users which were executing a hit at the end of the interval.
+	BusyWorkers int          `json:"busy_workers"`
+	Latency     []Percentile `json:"latency"`
+}
+
+// recordTimeline samples the results of the load test in the configured interval until it has finished.
+func (lt *LoadTest) recordTimeline() {
+	if lt.timelineInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(lt.timelineInterval)
+		defer ticker.Stop()
+
+		last := time.Duration(0)
+		for {
+			select {
+			case <-lt.done:
+				return
+			case <-ticker.C:
+				elapsed := lt.Elapsed()
+				sample := lt.resultAggregator.sample(elapsed - last)
+				sample.Offset = elapsed
+				if lt.Pacer != nil {
+					sample.ExpectedRate = lt.Pacer.Rate(elapsed)
+				}
+				workers := lt.WorkerStats()
+				sample.Workers = workers.Live
+				sample.BusyWorkers = workers.Busy
+				last = elapsed
+
+				lt.timelineMu.Lock()
+				lt.timeline = append(lt.timeline, sample)
+				lt.timelineMu.Unlock()
+			}
+		}
+	}()
+}
+
+// sample returns the results received since the last sample and starts a new interval.
+func (ra *resultAggregator) sample(interval time.Duration) TimelineSample {
+	latencies := ra.window.latencies.SnapshotAndReset()
+	total := ra.window.total.Swap(0)
+	failures := ra.window.failures.Swap(0)
+
+	sample := TimelineSample{
+		Requests: total,
+		Latency:  make([]Percentile, 0, len(timelinePercentiles)),
+	}
+	if interval > 0 {
+		sample.Rate = float64(total) / interval.Seconds()
+	}
+	if total > 0 {
+		sample.ErrorRate = float64(failures) / float64(total)
+	}
+	for _, p := range timelinePercentiles {
+		sample.Latency = append(sample.Latency, Percentile{
+			Percentile: p,
+			Value:      latencies.Percentile(p),
+		})
+	}
+
+	return sample
+}
@@ -0,0 +1,224 @@
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// subBucketBits defines the precision of the histogram. Each power of two range
+// is split into 2^(subBucketBits-1) linear buckets, which keeps the relative
+// error of a recorded value below 1/64 (~1.6%).
+const (
+	subBucketBits      = 7
+	subBucketCount     = 1 << subBucketBits
+	subBucketHalfCount = subBucketCount / 2
+	bucketCount        = (64-subBucketBits)*subBucketHalfCount + subBucketCount
+)
+
+// Histogram is a HDR style histogram for durations with a fixed memory footprint.
+//
+// It is safe to be used from multiple goroutines.
+type Histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	total  uint64
+	sum    float64
+	min    time.Duration
+	max    time.Duration
+}
+
+func New() *Histogram {
+	return &Histogram{
+		counts: make([]uint64, bucketCount),
+	}
+}
+
+// Record adds a single duration to the histogram. Negative values are recorded as zero.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[bucketIndex(uint64(d))]++
+	if h.total == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.total++
+	h.sum += float64(d)
+}
+
+// Merge adds all recorded values of other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	snapshot := other.Snapshot()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if snapshot.total == 0 {
+		return
+	}
+	for i, c := range snapshot.counts {
+		h.counts[i] += c
+	}
+	if h.total == 0 || snapshot.min < h.min {
+		h.min = snapshot.min
+	}
+	if snapshot.max > h.max {
+		h.max = snapshot.max
+	}
+	h.total += snapshot.total
+	h.sum += snapshot.sum
+}
+
+// Reset removes all recorded values.
+func (h *Histogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clear(h.counts)
+	h.total = 0
+	h.sum = 0
+	h.min = 0
+	h.max = 0
+}
+
+// Snapshot returns a copy of the histogram which is not affected by further recordings.
+func (h *Histogram) Snapshot() *Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+
+	return &Histogram{
+		counts: counts,
+		total:  h.total,
+		sum:    h.sum,
+		min:    h.min,
+		max:    h.max,
+	}
+}
+
+// SnapshotAndReset returns a copy of the histogram and removes all recorded values in one step.
+func (h *Histogram) SnapshotAndReset() *Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := &Histogram{
+		counts: h.counts,
+		total:  h.total,
+		sum:    h.sum,
+		min:    h.min,
+		max:    h.max,
+	}
+
+	h.counts = make([]uint64, bucketCount)
+	h.total = 0
+	h.sum = 0
+	h.min = 0
+	h.max = 0
+
+	return snapshot
+}
+
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.total
+}
+
+func (h *Histogram) Min() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.min
+}
+
+func (h *Histogram) Max() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.max
+}
+
+func (h *Histogram) Mean() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.total))
+}
+
+// Percentile returns the value below which the given percentage (0-100) of the
+// recorded values fall. The result is the highest value equivalent to the bucket
+// the percentile falls into, capped by the maximum recorded value.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.total == 0 {
+		return 0
+	}
+	if p >= 100 {
+		return h.max
+	}
+	if p <= 0 {
+		return h.min
+	}
+
+	target := uint64(math.Ceil(p / 100 * float64(h.total)))
+	var cumulative uint64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= target {
+			return min(time.Duration(bucketUpperBound(i)), h.max)
+		}
+	}
+
+	return h.max
+}
+
+// Buckets calls fn for every non-empty bucket with its inclusive upper bound and count,
+// in ascending order.
+func (h *Histogram) Buckets(fn func(upperBound time.Duration, count uint64)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, c := range h.counts {
+		if c > 0 {
+			fn(time.Duration(bucketUpperBound(i)), c)
+		}
+	}
+}
+
+func bucketIndex(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits
+	return shift*subBucketHalfCount + int(v>>shift)
+}
+
+func bucketUpperBound(index int) uint64 {
+	if index < subBucketCount {
+		return uint64(index)
+	}
+	shift := index/subBucketHalfCount - 1
+	sub := uint64(index - shift*subBucketHalfCount)
+	upper := (sub+1)<<shift - 1
+	if upper > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return upper
+}
@@ -0,0 +1,116 @@
+package histogram
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	h := New()
+	for i := 1; i <= 10000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	tests := []struct {
+		percentile float64
+		want       time.Duration
+	}{
+		{percentile: -1, want: time.Microsecond},
+		{percentile: 0, want: time.Microsecond},
+		{percentile: 0.01, want: time.Microsecond},
+		{percentile: 50, want: 5 * time.Millisecond},
+		{percentile: 90, want: 9 * time.Millisecond},
+		{percentile: 99, want: 9900 * time.Microsecond},
+		{percentile: 99.9, want: 9990 * time.Microsecond},
+		{percentile: 100, want: 10 * time.Millisecond},
+		{percentile: 150, want: 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := h.Percentile(tt.percentile)
+		// the result is the upper bound of the bucket, which is at most 1/64 above the value
+		if got < tt.want || float64(got-tt.want) > float64(tt.want)/64 {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.percentile, got, tt.want)
+		}
+		if got > h.Max() {
+			t.Errorf("Percentile(%v) = %v is above the maximum %v", tt.percentile, got, h.Max())
+		}
+	}
+}
+
+func TestPercentileBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []time.Duration
+	}{
+		{name: "empty"},
+		{name: "zero", values: []time.Duration{0, 0}},
+		{name: "small", values: []time.Duration{1, 2, 3, 127, 128, 129}},
+		{name: "single", values: []time.Duration{1234567 * time.Nanosecond}},
+		{name: "negative", values: []time.Duration{-time.Second, time.Second}},
+		{name: "large", values: []time.Duration{time.Hour, 1000 * time.Hour, math.MaxInt64}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New()
+			for _, v := range tt.values {
+				h.Record(v)
+			}
+
+			previous := time.Duration(0)
+			for p := 0.0; p <= 100; p += 0.5 {
+				got := h.Percentile(p)
+				if got < h.Min() || got > h.Max() {
+					t.Fatalf("Percentile(%v) = %v is outside of [%v, %v]", p, got, h.Min(), h.Max())
+				}
+				if got < previous {
+					t.Fatalf("Percentile(%v) = %v is below the previous percentile %v", p, got, previous)
+				}
+				previous = got
+			}
+		})
+	}
+}
+
+func TestBucketBounds(t *testing.T) {
+	values := []uint64{0, 1, 127, 128, 129, 255, 256, 1000, 1 << 20, 1<<20 + 1, 1<<40 - 1, math.MaxInt64}
+	for _, v := range values {
+		index := bucketIndex(v)
+		if index < 0 || index >= bucketCount {
+			t.Fatalf("bucketIndex(%d) = %d is out of range", v, index)
+		}
+		upper := bucketUpperBound(index)
+		if upper < v {
+			t.Errorf("the upper bound %d of the bucket of %d is below the value", upper, v)
+		}
+		if float64(upper-v) > float64(v)/64 {
+			t.Errorf("the upper bound %d of the bucket of %d is more than 1/64 above the value", upper, v)
+		}
+		if index > 0 && bucketUpperBound(index-1) >= v {
+			t.Errorf("%d belongs to the previous bucket with the upper bound %d", v, bucketUpperBound(index-1))
+		}
+	}
+}
+
+func TestMergeAndReset(t *testing.T) {
+	a, b := New(), New()
+	a.Record(time.Millisecond)
+	a.Record(3 * time.Millisecond)
+	b.Record(2 * time.Millisecond)
+	b.Record(10 * time.Millisecond)
+
+	a.Merge(b)
+	a.Merge(New())
+	if a.Count() != 4 || a.Min() != time.Millisecond || a.Max() != 10*time.Millisecond || a.Mean() != 4*time.Millisecond {
+		t.Errorf("merged histogram has count %d, min %v, max %v and mean %v", a.Count(), a.Min(), a.Max(), a.Mean())
+	}
+
+	snapshot := a.SnapshotAndReset()
+	if snapshot.Count() != 4 || a.Count() != 0 || a.Percentile(50) != 0 {
+		t.Errorf("snapshot has count %d, reset histogram count %d", snapshot.Count(), a.Count())
+	}
+	a.Record(5 * time.Millisecond)
+	if a.Min() != 5*time.Millisecond || snapshot.Max() != 10*time.Millisecond {
+		t.Errorf("reset histogram has min %v, snapshot max %v", a.Min(), snapshot.Max())
+	}
+}
@@ -0,0 +1,324 @@
+package goload
+
+import (
+	"fmt"
+	"github.com/mroth/weightedrand/v2"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// userScheduleInterval is the interval in which the number of virtual users is adjusted to the schedule.
+const userScheduleInterval = 100 * time.Millisecond
+
+// UserStage changes the number of virtual users linearly to Target within Duration.
+// A zero duration changes the number immediately.
+type UserStage struct {
+	Target   int
+	Duration time.Duration
+}
+
+// ThinkTime returns the pause of a virtual user between two iterations.
+type ThinkTime func() time.Duration
+
+// ConstantThinkTime pauses for the same duration after every iteration.
+func ConstantThinkTime(d time.Duration) ThinkTime {
+	return func() time.Duration {
+		return d
+	}
+}
+
+// UniformThinkTime pauses for a random duration between min and max.
+func UniformThinkTime(min, max time.Duration) ThinkTime {
+	return func() time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rand.Int63n(int64(max-min)))
+	}
+}
+
+// ExponentialThinkTime pauses for exponentially distributed durations with the given mean.
+func ExponentialThinkTime(mean time.Duration) ThinkTime {
+	return func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	}
+}
+
+// NormalThinkTime pauses for normally distributed durations, negative values are cut off at zero.
+func NormalThinkTime(mean, stddev time.Duration) ThinkTime {
+	return func() time.Duration {
+		return max(time.Duration(rand.NormFloat64()*float64(stddev))+mean, 0)
+	}
+}
+
+// VirtualUserStats summarizes the iterations of the virtual users of a closed-model run.
+type VirtualUserStats struct {
+	// Peak is the highest number of virtual users which were running at the same time.
+	Peak       int   `json:"peak"`
+	Iterations int64 `json:"iterations"`
+	// MinRate, MeanRate and MaxRate are the iterations per second of the virtual users.
+	MinRate  float64 `json:"min_rate"`
+	MeanRate float64 `json:"mean_rate"`
+	MaxRate  float64 `json:"max_rate"`
+	// Started is the number of virtual users started during the run.
+	Started int `json:"started"`
+	// Users contains the virtual users which are still running. Stopped users are only
+	// part of the totals above, so the report doesn't grow with every user ever started.
+	Users []UserStats `json:"users"`
+}
+
+// UserStats contains the iterations of a single virtual user.
+type UserStats struct {
+	User       int   `json:"user"`
+	Iterations int64 `json:"iterations"`
+	// Active is the time the virtual user was running.
+	Active time.Duration `json:"active_ns"`
+	// Rate is the number of iterations per second while the virtual user was running.
+	Rate float64 `json:"rate"`
+}
+
+func validateUserStages(stages []UserStage) error {
+	for i, stage := range stages {
+		if stage.Target < 0 {
+			return fmt.Errorf("user stage %d: target can't be negative", i+1)
+		}
+		if stage.Duration < 0 {
+			return fmt.Errorf("user stage %d: duration can't be negative", i+1)
+		}
+	}
+	return nil
+}
+
+// targetUsers returns the number of virtual users which should be running at the elapsed time.
+func targetUsers(stages []UserStage, elapsed time.Duration) int {
+	start := time.Duration(0)
+	users := 0
+	for _, stage := range stages {
+		if elapsed < start+stage.Duration {
+			progress := float64(elapsed-start) / float64(stage.Duration)
+			return users + int(math.Round(float64(stage.Target-users)*progress))
+		}
+		start += stage.Duration
+		users = stage.Target
+	}
+	return users
+}
+
+// usersDuration returns the total duration of all stages.
+func usersDuration(stages []UserStage) time.Duration {
+	total := time.Duration(0)
+	for _, stage := range stages {
+		total += stage.Duration
+	}
+	return total
+}
+
+type virtualUser struct {
+	id         int
+	stop       chan struct{}
+	iterations atomic.Int64
+	// vars are kept between the iterations of the user
+	vars *Variables
+
+	mu      sync.Mutex
+	started time.Time
+	stopped time.Time
+}
+
+// userTotals aggregates the iterations and rates of virtual users.
+type userTotals struct {
+	count      int
+	iterations int64
+	rateSum    float64
+	minRate    float64
+	maxRate    float64
+}
+
+func (t *userTotals) add(stats UserStats) {
+	if t.count == 0 || stats.Rate < t.minRate {
+		t.minRate = stats.Rate
+	}
+	t.maxRate = max(t.maxRate, stats.Rate)
+	t.rateSum += stats.Rate
+	t.iterations += stats.Iterations
+	t.count++
+}
+
+func (u *virtualUser) stats(now time.Time) UserStats {
+	stats := UserStats{
+		User:       u.id,
+		Iterations: u.iterations.Load(),
+		Active:     u.active(now),
+	}
+	if stats.Active > 0 {
+		stats.Rate = float64(stats.Iterations) / stats.Active.Seconds()
+	}
+	return stats
+}
+
+func (u *virtualUser) active(now time.Time) time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.stopped.IsZero() {
+		now = u.stopped
+	}
+	return now.Sub(u.started)
+}
+
+// runUsers executes the closed model: each virtual user sends a hit, waits for the think time and
+// starts over. The number of virtual users follows the configured stages.
+func (r *Runner) runUsers(chooser *weightedrand.Chooser[Executor, int], began time.Time, du time.Duration, results chan<- *Result) {
+	var wg sync.WaitGroup
+	var running []*virtualUser
+
+	defer func() {
+		for _, user := range running {
+			close(user.stop)
+		}
+		wg.Wait()
+		close(results)
+		r.Stop()
+	}()
+
+	for {
+		elapsed := time.Since(began)
+		if du > 0 && elapsed >= du {
+			return
+		}
+
+		target := targetUsers(r.userStages, elapsed)
+		if du == 0 && target == 0 && elapsed >= usersDuration(r.userStages) {
+			// without a duration, the execution ends once all stages are completed
+			return
+		}
+		for len(running) < target {
+			r.usersMu.Lock()
+			r.startedUsers++
+			user := &virtualUser{
+				id:      r.startedUsers,
+				stop:    make(chan struct{}),
+				vars:    NewVariables(),
+				started: time.Now(),
+			}
+			if r.users == nil {
+				r.users = map[int]*virtualUser{}
+			}
+			r.users[user.id] = user
+			r.usersMu.Unlock()
+
+			running = append(running, user)
+			wg.Add(1)
+			go r.runUser(user, chooser, began, &wg, results)
+		}
+		for len(running) > target {
+			close(running[len(running)-1].stop)
+			running = running[:len(running)-1]
+		}
+		if int64(len(running)) > r.peakUsers.Load() {
+			r.peakUsers.Store(int64(len(running)))
+		}
+
+		wait := userScheduleInterval
+		if du > 0 {
+			wait = min(wait, du-elapsed)
+		}
+		if !r.sleep(wait) {
+			return
+		}
+	}
+}
+
+func (r *Runner) runUser(user *virtualUser, chooser *weightedrand.Chooser[Executor, int], began time.Time, wg *sync.WaitGroup, results chan<- *Result) {
+	defer wg.Done()
+
+	r.liveWorkers.Add(1)
+	defer r.liveWorkers.Add(-1)
+
+	defer func() {
+		user.mu.Lock()
+		user.stopped = time.Now()
+		user.mu.Unlock()
+
+		r.usersMu.Lock()
+		r.stoppedUsers.add(user.stats(user.stopped))
+		delete(r.users, user.id)
+		r.usersMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-user.stop:
+			return
+		case <-r.stopch:
+			return
+		default:
+		}
+
+		iteration := r.hit(chooser.Pick(), began, time.Time{}, user.vars)
+		user.iterations.Add(1)
+		for _, result := range iteration {
+			result.User = user.id
+			results <- result
+		}
+
+		if r.thinkTime == nil {
+			continue
+		}
+		if think := r.thinkTime(); think > 0 {
+			timer := time.NewTimer(think)
+			select {
+			case <-timer.C:
+			case <-user.stop:
+				timer.Stop()
+				return
+			case <-r.stopch:
+				timer.Stop()
+				return
+			}
+		}
+	}
+}
+
+// virtualUserStats summarizes the iterations of all virtual users started so far. It returns nil
+// if the runner doesn't use the closed model.
+func (r *Runner) virtualUserStats() *VirtualUserStats {
+	if len(r.userStages) == 0 {
+		return nil
+	}
+
+	r.usersMu.Lock()
+	totals := r.stoppedUsers
+	started := r.startedUsers
+	users := make([]*virtualUser, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	r.usersMu.Unlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].id < users[j].id })
+
+	stats := &VirtualUserStats{
+		Peak:    int(r.peakUsers.Load()),
+		Started: started,
+		Users:   make([]UserStats, 0, len(users)),
+	}
+
+	now := time.Now()
+	for _, user := range users {
+		userStats := user.stats(now)
+		totals.add(userStats)
+		stats.Users = append(stats.Users, userStats)
+	}
+	stats.Iterations = totals.iterations
+	stats.MinRate = totals.minRate
+	stats.MaxRate = totals.maxRate
+	if totals.count > 0 {
+		stats.MeanRate = totals.rateSum / float64(totals.count)
+	}
+
+	return stats
+}
@@ -0,0 +1,105 @@
+package goload
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTargetUsers(t *testing.T) {
+	stages := []UserStage{
+		{Target: 10, Duration: time.Second},
+		{Target: 10, Duration: time.Second},
+		{Target: 2, Duration: 0},
+		{Target: 0, Duration: time.Second},
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		users   int
+	}{
+		{elapsed: 0, users: 0},
+		{elapsed: 500 * time.Millisecond, users: 5},
+		{elapsed: time.Second, users: 10},
+		{elapsed: 1500 * time.Millisecond, users: 10},
+		{elapsed: 2 * time.Second, users: 2},
+		{elapsed: 2500 * time.Millisecond, users: 1},
+		{elapsed: 3 * time.Second, users: 0},
+		{elapsed: time.Hour, users: 0},
+	}
+	for _, tt := range tests {
+		if users := targetUsers(stages, tt.elapsed); users != tt.users {
+			t.Errorf("targetUsers(%s) = %d, expected %d", tt.elapsed, users, tt.users)
+		}
+	}
+}
+
+func TestVirtualUsersLifecycle(t *testing.T) {
+	var mu sync.Mutex
+	iterations := map[int]int64{}
+
+	lt, err := New(
+		WithVirtualUsers(
+			UserStage{Target: 3, Duration: 0},
+			UserStage{Target: 3, Duration: 300 * time.Millisecond},
+			UserStage{Target: 1, Duration: 0},
+			UserStage{Target: 1, Duration: 300 * time.Millisecond},
+			UserStage{Target: 0, Duration: 0},
+		),
+		WithThinkTime(ConstantThinkTime(10*time.Millisecond)),
+		WithExecutors(NewGenericExecutor("noop", func(ctx context.Context) error { return nil })),
+		WithAdditionalResultHandler(func(_ *LoadTest, result *Result) {
+			mu.Lock()
+			defer mu.Unlock()
+			iterations[result.User]++
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the load test has no duration, so it only ends because the stages are completed
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := lt.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("the load test didn't end after the last stage")
+	}
+
+	stats := report.VirtualUsers
+	if stats == nil {
+		t.Fatal("expected virtual user stats")
+	}
+	if stats.Peak != 3 || stats.Started != 3 {
+		t.Errorf("peak %d and started %d, expected 3 users", stats.Peak, stats.Started)
+	}
+	// all users are stopped at the end of the run, so they are only part of the totals
+	if len(stats.Users) != 0 {
+		t.Errorf("expected no running users, got %d", len(stats.Users))
+	}
+	if stats.Iterations != report.Total.Requests {
+		t.Errorf("%d iterations, expected %d", stats.Iterations, report.Total.Requests)
+	}
+	if stats.MinRate <= 0 || stats.MinRate > stats.MeanRate || stats.MeanRate > stats.MaxRate {
+		t.Errorf("unexpected rates: min %f, mean %f, max %f", stats.MinRate, stats.MeanRate, stats.MaxRate)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for user := 1; user <= 3; user++ {
+		if iterations[user] == 0 {
+			t.Errorf("user %d didn't send any hits", user)
+		}
+	}
+	if len(iterations) != 3 {
+		t.Errorf("hits were sent by the users %v, expected 1 to 3", iterations)
+	}
+	// the first user keeps running while the others are stopped after the first stage
+	if iterations[1] <= iterations[2] || iterations[1] <= iterations[3] {
+		t.Errorf("the remaining user should send the most hits: %v", iterations)
+	}
+}